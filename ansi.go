@@ -0,0 +1,173 @@
+package termutil
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Splits a line containing ANSI escape sequences into styled runs, starting in
+// the given style, like less -R. SGR sequences (colours and attributes) change
+// the style; other CSI and OSC sequences are dropped. Returns the runs, the
+// plain text of the line, and the style in effect at the end of it, since
+// colours carry on from one line to the next. An unfinished sequence at the
+// end of s is dropped.
+func parseANSI(s string, style tcell.Style) (StyledLine, string, tcell.Style) {
+	line := StyledLine{}
+	var plain, text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			line = append(line, StyledRun{text.String(), style})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		if s[i] != 0x1b {
+			j := strings.IndexByte(s[i:], 0x1b)
+			if j < 0 {
+				j = len(s) - i
+			}
+			text.WriteString(s[i : i+j])
+			plain.WriteString(s[i : i+j])
+			i += j
+			continue
+		}
+		if i+1 >= len(s) {
+			break
+		}
+		switch s[i+1] {
+		case '[':
+			// CSI: parameter bytes, intermediate bytes, then a final byte.
+			j := i + 2
+			for j < len(s) && s[j] >= 0x30 && s[j] <= 0x3f {
+				j++
+			}
+			params := s[i+2 : j]
+			for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+				j++
+			}
+			if j >= len(s) {
+				i = len(s)
+				break
+			}
+			if s[j] == 'm' {
+				flush()
+				style = applySGR(style, params)
+			}
+			i = j + 1
+		case ']':
+			// OSC: ends with BEL or ESC \.
+			j := i + 2
+			for j < len(s) && s[j] != 0x07 && !(s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\') {
+				j++
+			}
+			if j < len(s) && s[j] == 0x1b {
+				j++
+			}
+			i = j + 1
+		default:
+			// Other escapes: intermediate bytes, then a final byte.
+			j := i + 1
+			for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+				j++
+			}
+			i = j + 1
+		}
+	}
+	flush()
+	return line, plain.String(), style
+}
+
+// Applies the parameters of an SGR sequence (the part between "ESC [" and "m")
+// to a style.
+func applySGR(style tcell.Style, params string) tcell.Style {
+	fields := strings.Split(params, ";")
+	codes := make([]int, len(fields))
+	for i, f := range fields {
+		// Sub-parameters, like 4:3 for a curly underline, are ignored.
+		if c := strings.IndexByte(f, ':'); c >= 0 {
+			f = f[:c]
+		}
+		codes[i], _ = strconv.Atoi(f)
+	}
+	for i := 0; i < len(codes); i++ {
+		code := codes[i]
+		switch {
+		case code == 0:
+			style = tcell.StyleDefault
+		case code == 1:
+			style = style.Bold(true)
+		case code == 2:
+			style = style.Dim(true)
+		case code == 3:
+			style = style.Italic(true)
+		case code == 4:
+			style = style.Underline(true)
+		case code == 5 || code == 6:
+			style = style.Blink(true)
+		case code == 7:
+			style = style.Reverse(true)
+		case code == 9:
+			style = style.StrikeThrough(true)
+		case code == 22:
+			style = style.Bold(false).Dim(false)
+		case code == 23:
+			style = style.Italic(false)
+		case code == 24:
+			style = style.Underline(false)
+		case code == 25:
+			style = style.Blink(false)
+		case code == 27:
+			style = style.Reverse(false)
+		case code == 29:
+			style = style.StrikeThrough(false)
+		case code >= 30 && code <= 37:
+			style = style.Foreground(tcell.PaletteColor(code - 30))
+		case code == 39:
+			style = style.Foreground(tcell.ColorDefault)
+		case code >= 40 && code <= 47:
+			style = style.Background(tcell.PaletteColor(code - 40))
+		case code == 49:
+			style = style.Background(tcell.ColorDefault)
+		case code >= 90 && code <= 97:
+			style = style.Foreground(tcell.PaletteColor(code - 90 + 8))
+		case code >= 100 && code <= 107:
+			style = style.Background(tcell.PaletteColor(code - 100 + 8))
+		case code == 38 || code == 48:
+			var c tcell.Color
+			c, i = sgrColor(codes, i)
+			if c == tcell.ColorDefault {
+				continue
+			}
+			if code == 38 {
+				style = style.Foreground(c)
+			} else {
+				style = style.Background(c)
+			}
+		}
+	}
+	return style
+}
+
+// Reads an extended colour (5;n or 2;r;g;b) following the 38 or 48 at
+// codes[i]. Returns the colour, or ColorDefault if it's malformed, and the
+// index of the last code used.
+func sgrColor(codes []int, i int) (tcell.Color, int) {
+	if i+1 >= len(codes) {
+		return tcell.ColorDefault, i
+	}
+	switch codes[i+1] {
+	case 5:
+		if i+2 < len(codes) && codes[i+2] >= 0 && codes[i+2] < 256 {
+			return tcell.PaletteColor(codes[i+2]), i + 2
+		}
+		return tcell.ColorDefault, len(codes)
+	case 2:
+		if i+4 < len(codes) {
+			return tcell.NewRGBColor(int32(codes[i+2]), int32(codes[i+3]), int32(codes[i+4])), i + 4
+		}
+		return tcell.ColorDefault, len(codes)
+	}
+	return tcell.ColorDefault, len(codes)
+}
@@ -0,0 +1,65 @@
+package termutil
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseANSI(t *testing.T) {
+	def := tcell.StyleDefault
+	red := def.Foreground(tcell.PaletteColor(1))
+	tests := []struct {
+		name  string
+		in    string
+		start tcell.Style
+		runs  StyledLine
+		plain string
+		end   tcell.Style
+	}{
+		{"plain", "hello", def, StyledLine{{"hello", def}}, "hello", def},
+		{"empty", "", def, StyledLine{}, "", def},
+		{"colour and reset", "\x1b[31mred\x1b[0m plain", def,
+			StyledLine{{"red", red}, {" plain", def}}, "red plain", def},
+		{"empty reset", "\x1b[1mx\x1b[my", def,
+			StyledLine{{"x", def.Bold(true)}, {"y", def}}, "xy", def},
+		{"carried over", "still red", red, StyledLine{{"still red", red}}, "still red", red},
+		{"left set", "\x1b[1;4mon", def,
+			StyledLine{{"on", def.Bold(true).Underline(true)}}, "on", def.Bold(true).Underline(true)},
+		{"256 colours", "\x1b[38;5;208mx", def,
+			StyledLine{{"x", def.Foreground(tcell.PaletteColor(208))}}, "x", def.Foreground(tcell.PaletteColor(208))},
+		{"truecolour background", "\x1b[48;2;1;2;3mx", def,
+			StyledLine{{"x", def.Background(tcell.NewRGBColor(1, 2, 3))}}, "x", def.Background(tcell.NewRGBColor(1, 2, 3))},
+		{"bright", "\x1b[91;102mx", def,
+			StyledLine{{"x", def.Foreground(tcell.PaletteColor(9)).Background(tcell.PaletteColor(10))}}, "x",
+			def.Foreground(tcell.PaletteColor(9)).Background(tcell.PaletteColor(10))},
+		{"attributes off", "\x1b[1;7m\x1b[22;27mx", def, StyledLine{{"x", def}}, "x", def},
+		{"default colours", "\x1b[39;49mx", red.Background(tcell.ColorBlue), StyledLine{{"x", def}}, "x", def},
+		{"truncated 256", "\x1b[38;5mx", def, StyledLine{{"x", def}}, "x", def},
+		{"truncated truecolour", "\x1b[38;2;1mx", def, StyledLine{{"x", def}}, "x", def},
+		{"other CSI dropped", "\x1b[2Ka\x1b[10;20Hb", def, StyledLine{{"ab", def}}, "ab", def},
+		{"OSC with BEL", "\x1b]0;title\x07a", def, StyledLine{{"a", def}}, "a", def},
+		{"OSC with ST", "\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\", def, StyledLine{{"link", def}}, "link", def},
+		{"charset escape", "\x1b(Ba", def, StyledLine{{"a", def}}, "a", def},
+		{"unfinished CSI", "a\x1b[3", def, StyledLine{{"a", def}}, "a", def},
+		{"lone escape", "a\x1b", def, StyledLine{{"a", def}}, "a", def},
+	}
+	for _, tt := range tests {
+		runs, plain, end := parseANSI(tt.in, tt.start)
+		if len(runs) != len(tt.runs) {
+			t.Errorf("%s: got %d runs %v, want %v", tt.name, len(runs), runs, tt.runs)
+		} else {
+			for i := range runs {
+				if runs[i] != tt.runs[i] {
+					t.Errorf("%s: run %d is %v, want %v", tt.name, i, runs[i], tt.runs[i])
+				}
+			}
+		}
+		if plain != tt.plain {
+			t.Errorf("%s: plain text is %q, want %q", tt.name, plain, tt.plain)
+		}
+		if end != tt.end {
+			t.Errorf("%s: ends in %v, want %v", tt.name, end, tt.end)
+		}
+	}
+}
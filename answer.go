@@ -0,0 +1,82 @@
+package termutil
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Answer is the user's reply to a question asked with Ask.
+type Answer int
+
+const (
+	// AnswerCancel means the user pressed C-g.
+	AnswerCancel Answer = iota
+	AnswerYes
+	AnswerNo
+	// AnswerAll means yes to this and every following question.
+	AnswerAll
+	// AnswerQuit means no to this and every following question.
+	AnswerQuit
+)
+
+func (a Answer) String() string {
+	switch a {
+	case AnswerCancel:
+		return "cancel"
+	case AnswerYes:
+		return "yes"
+	case AnswerNo:
+		return "no"
+	case AnswerAll:
+		return "all"
+	case AnswerQuit:
+		return "quit"
+	}
+	return "unknown"
+}
+
+// AnswerKey binds a key (as returned by ParseTcellEvent) to an Answer.
+type AnswerKey struct {
+	Key    string
+	Answer Answer
+}
+
+// AnswerSet is the set of answers offered by Ask, in the order they're shown.
+type AnswerSet []AnswerKey
+
+var (
+	// YesNoAnswers offers y and n.
+	YesNoAnswers = AnswerSet{{"y", AnswerYes}, {"n", AnswerNo}}
+	// QueryAnswers offers y, n, ! (yes to all) and q (quit), like Emacs'
+	// query-replace.
+	QueryAnswers = AnswerSet{{"y", AnswerYes}, {"n", AnswerNo}, {"!", AnswerAll}, {"q", AnswerQuit}}
+)
+
+// Displays the prompt p followed by the keys in answers, and waits for the user
+// to press one of them. Returns the matching Answer, or AnswerCancel if the user
+// pressed C-g.
+func Ask(screen tcell.Screen, p string, refresh func(tcell.Screen, int, int), answers AnswerSet) Answer {
+	return AskTimeout(screen, p, refresh, answers, 0, AnswerCancel)
+}
+
+// As Ask, but if the user hasn't answered before timeout runs out, def is
+// returned. The seconds remaining are shown after the prompt. A timeout of zero
+// or less waits forever.
+func AskTimeout(screen tcell.Screen, p string, refresh func(tcell.Screen, int, int), answers AnswerSet, timeout time.Duration, def Answer) Answer {
+	keys := make([]string, len(answers))
+	for i, a := range answers {
+		keys[i] = a.Key
+	}
+	pm := pressKeyPrompt(p, keys)
+	key := pressKey(screen, pm, refresh, timeout, append(keys, "C-g"))
+	if key == "" {
+		return def
+	}
+	for _, a := range answers {
+		if a.Key == key {
+			return a.Answer
+		}
+	}
+	return AnswerCancel
+}
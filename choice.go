@@ -0,0 +1,570 @@
+package termutil
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ChoiceItem is a single entry in a menu shown by ChoiceItemIndex. Only Label
+// is required; the zero value of everything else gives a plain, selectable
+// entry, which is what ChoiceIndex uses for each of its strings.
+type ChoiceItem struct {
+	// Label is the text shown for the entry.
+	Label string
+	// Description is drawn dimmed in a column to the right of the labels.
+	Description string
+	// Style is used to draw the label.
+	Style tcell.Style
+	// Disabled entries are drawn dimmed and are skipped by navigation.
+	Disabled bool
+	// Header entries are drawn in bold and can't be selected.
+	Header bool
+	// Separator entries are drawn as a horizontal rule and can't be selected.
+	Separator bool
+	// Key is an accelerator key for the entry, as returned by
+	// ParseTcellEvent. If it's a character in the label, that character is
+	// underlined; otherwise it's shown in brackets before the label. In
+	// sources other than StringSource and ItemSource, only the entries on
+	// the screen are searched for accelerators.
+	Key string
+
+	// accel is the byte offset in Label of the character marked with an
+	// ampersand by AcceleratorItems, plus one; 0 if none was marked.
+	accel int
+}
+
+// Selectable returns true if the entry can be selected by the user.
+func (item ChoiceItem) Selectable() bool {
+	return !item.Disabled && !item.Header && !item.Separator
+}
+
+// display returns the label as it's drawn, and the byte offset of the rune to
+// underline as the accelerator, or -1 if there isn't one.
+func (item ChoiceItem) display() (string, int) {
+	if item.Key == "" {
+		return item.Label, -1
+	}
+	if i := item.accel - 1; i >= 0 && i < len(item.Label) {
+		return item.Label, i
+	}
+	if i := acceleratorIndex(item.Label, item.Key); i >= 0 {
+		return item.Label, i
+	}
+	return "[" + item.Key + "] " + item.Label, 1
+}
+
+// acceleratorIndex returns the byte offset of the accelerator key in label, or
+// -1 if it isn't there.
+func acceleratorIndex(label, key string) int {
+	if utf8.RuneCountInString(key) != 1 {
+		return -1
+	}
+	for i, ru := range label {
+		if strings.EqualFold(string(ru), key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Prints label with the rune at byte offset accel underlined, if accel isn't
+// negative, skipping cells outside [left, right). Returns the column after the
+// last rune.
+func printAccelerated(screen tcell.Screen, x, y, left, right int, label string, accel int, style tcell.Style) int {
+	if accel < 0 {
+		return printStringClip(screen, x, y, left, right, label, style)
+	}
+	_, size := utf8.DecodeRuneInString(label[accel:])
+	x = printStringClip(screen, x, y, left, right, label[:accel], style)
+	x = printStringClip(screen, x, y, left, right, label[accel:accel+size], themeOver(style, "accelerator"))
+	return printStringClip(screen, x, y, left, right, label[accel+size:], style)
+}
+
+// Turns choices into ChoiceItems with accelerator keys. A character preceded by
+// an ampersand (as in "&Open") becomes the entry's accelerator, and the
+// ampersand is removed; write "&&" for an ampersand. The first nine entries
+// without one are given the keys 1 to 9 according to their position.
+func AcceleratorItems(choices []string) []ChoiceItem {
+	items := make([]ChoiceItem, len(choices))
+	for i, s := range choices {
+		var at int
+		items[i].Label, items[i].Key, at = parseAccelerator(s)
+		items[i].accel = at + 1
+		if items[i].Key == "" && i < 9 {
+			items[i].Key = strconv.Itoa(i + 1)
+		}
+	}
+	return items
+}
+
+// parseAccelerator removes the ampersand marking the accelerator in s, and
+// returns the remaining label, the accelerator key (or "" if there isn't one),
+// and the byte offset of the marked character in the label (or -1). "&&" stands
+// for a literal ampersand.
+func parseAccelerator(s string) (string, string, int) {
+	var label strings.Builder
+	key := ""
+	at := -1
+	for j := 0; j < len(s); j++ {
+		if s[j] == '&' && j+1 < len(s) {
+			j++
+			if s[j] != '&' && key == "" {
+				r, _ := utf8.DecodeRuneInString(s[j:])
+				key = strings.ToLower(string(r))
+				at = label.Len()
+			}
+		}
+		label.WriteByte(s[j])
+	}
+	return label.String(), key, at
+}
+
+// Set UseMouse to true if you've called EnableMouse on your screen and want
+// menus to respond to the mouse: the wheel scrolls, clicking an entry selects
+// it, and clicking the selected entry (or double-clicking) accepts it. It's off
+// by default so that apps which handle the mouse themselves see no change.
+var UseMouse = false
+
+// The mouse buttons that stay down until they're released, unlike the wheel.
+const heldButtons = tcell.Button1 | tcell.Button2 | tcell.Button3
+
+// ChoiceSource supplies the entries of a menu on demand, so that very long
+// lists don't need to be built up front. Only the entries on screen are
+// fetched each time the menu is drawn.
+type ChoiceSource interface {
+	// Len returns the number of entries.
+	Len() int
+	// At returns entry i, where 0 <= i < Len().
+	At(i int) ChoiceItem
+}
+
+// StringSource is a ChoiceSource of plain strings.
+type StringSource []string
+
+func (s StringSource) Len() int {
+	return len(s)
+}
+
+func (s StringSource) At(i int) ChoiceItem {
+	return ChoiceItem{Label: s[i]}
+}
+
+// ItemSource is a ChoiceSource of ChoiceItems.
+type ItemSource []ChoiceItem
+
+func (s ItemSource) Len() int {
+	return len(s)
+}
+
+func (s ItemSource) At(i int) ChoiceItem {
+	return s[i]
+}
+
+// As ChoiceIndex, but takes a slice of ChoiceItems, allowing for descriptions,
+// styles, headers, separators and disabled entries.
+func ChoiceItemIndex(screen tcell.Screen, title string, items []ChoiceItem, def int) int {
+	return ChoiceItemIndexCallback(screen, title, items, def, nil)
+}
+
+// As ChoiceIndexCallback, but takes a slice of ChoiceItems.
+func ChoiceItemIndexCallback(screen tcell.Screen, title string, items []ChoiceItem, def int, f func(tcell.Screen, int, int, int)) int {
+	return ChoiceSourceIndexCallback(screen, title, ItemSource(items), def, f)
+}
+
+// As ChoiceIndex, but the entries come from a ChoiceSource. The time taken to
+// draw the menu depends on the height of the screen, not the number of entries.
+func ChoiceSourceIndex(screen tcell.Screen, title string, source ChoiceSource, def int) int {
+	return ChoiceSourceIndexCallback(screen, title, source, def, nil)
+}
+
+// As ChoiceIndexCallback, but the entries come from a ChoiceSource.
+func ChoiceSourceIndexCallback(screen tcell.Screen, title string, source ChoiceSource, def int, f func(tcell.Screen, int, int, int)) int {
+	menu := choiceMenu{
+		title:    title,
+		source:   source,
+		def:      def,
+		callback: f,
+	}
+	return menu.run(screen)
+}
+
+// choiceMenu holds everything needed to draw and run a menu.
+type choiceMenu struct {
+	title    string
+	source   ChoiceSource
+	def      int
+	callback func(tcell.Screen, int, int, int)
+	// popup draws the menu in a box over the screen's contents; px and py
+	// are where to put it (see popupRect).
+	popup  bool
+	px, py int
+	// status, if set, is called on each redraw and appended to the title.
+	status func() string
+	// preview, if set, is called to fill in the preview pane.
+	preview func(int) []StyledLine
+	// The layout of a slice source, which is only measured once.
+	measured        bool
+	desccol, bw, bh int
+	// The entries of an ItemSource with each accelerator key, built the
+	// first time a key might be one.
+	keys map[string][]int
+}
+
+// nextSelectable returns the first selectable index starting at from and
+// moving in direction dir (1 or -1), or -1 if there is none.
+func (m *choiceMenu) nextSelectable(from, dir int) int {
+	for i := from; i >= 0 && i < m.source.Len(); i += dir {
+		if m.source.At(i).Selectable() {
+			return i
+		}
+	}
+	return -1
+}
+
+// settle returns a selectable index as near to want as possible, looking in
+// direction dir first. If nothing is selectable, it returns fallback.
+func (m *choiceMenu) settle(want, dir, fallback int) int {
+	if want < 0 {
+		want = 0
+	} else if want >= m.source.Len() {
+		want = m.source.Len() - 1
+	}
+	if i := m.nextSelectable(want, dir); i >= 0 {
+		return i
+	}
+	if i := m.nextSelectable(want, -dir); i >= 0 {
+		return i
+	}
+	return fallback
+}
+
+// measure returns the column descriptions are drawn in (see descColumn) and,
+// for pop-ups, the size of the box. Slices are measured in full, but only
+// once, so the layout doesn't shift as the user scrolls; other sources may be
+// huge, so only the rows entries from offset on are measured.
+func (m *choiceMenu) measure(offset, rows int) (int, int, int) {
+	from, to := offset, offset+rows
+	switch m.source.(type) {
+	case StringSource, ItemSource:
+		if m.measured {
+			return m.desccol, m.bw, m.bh
+		}
+		from, to = 0, m.source.Len()
+	}
+	if to > m.source.Len() {
+		to = m.source.Len()
+	}
+	desccol := m.descColumn(from, to)
+	bw, bh := 0, 0
+	if m.popup {
+		bw, bh = m.popupSize(from, to, desccol)
+	}
+	switch m.source.(type) {
+	case StringSource, ItemSource:
+		m.measured = true
+		m.desccol, m.bw, m.bh = desccol, bw, bh
+	}
+	return desccol, bw, bh
+}
+
+// descColumn returns the column that descriptions are drawn in, relative to
+// the start of the labels, or -1 if no entry in [from, to) has a description.
+func (m *choiceMenu) descColumn(from, to int) int {
+	if _, ok := m.source.(StringSource); ok {
+		// Plain strings never have descriptions.
+		return -1
+	}
+	labelw := 0
+	hasdesc := false
+	for i := from; i < to; i++ {
+		item := m.source.At(i)
+		label, _ := item.display()
+		if w := RunewidthStr(label); w > labelw {
+			labelw = w
+		}
+		if item.Description != "" {
+			hasdesc = true
+		}
+	}
+	if !hasdesc {
+		return -1
+	}
+	return labelw + 2
+}
+
+// popupSize returns the size of the box needed to show the entries in
+// [from, to), which have their descriptions at desccol.
+func (m *choiceMenu) popupSize(from, to, desccol int) (int, int) {
+	w := RunewidthStr(m.title) + 4
+	for i := from; i < to; i++ {
+		item := m.source.At(i)
+		label, _ := item.display()
+		iw := RunewidthStr(label)
+		if desccol >= 0 && item.Description != "" {
+			iw = desccol + RunewidthStr(item.Description)
+		}
+		if iw+6 > w {
+			w = iw + 6
+		}
+	}
+	return w, m.source.Len() + 2
+}
+
+// Draws an item on row y; the menu's rows run from column left to right.
+func (m *choiceMenu) drawItem(screen tcell.Screen, y, left, right, cx, desccol int, item ChoiceItem) {
+	if item.Separator {
+		for i := left + 3; i < right; i++ {
+			screen.SetContent(i, y, '─', nil, themeStyle("menu.separator"))
+		}
+		return
+	}
+	style := item.Style
+	if item.Header {
+		style = themeOver(style, "menu.header")
+	} else if item.Disabled {
+		style = themeOver(style, "menu.disabled")
+	}
+	label, accel := item.display()
+	printAccelerated(screen, left+3-cx, y, left+3, right, label, accel, style)
+	if desccol >= 0 && item.Description != "" {
+		printStringClip(screen, left+3+desccol-cx, y, left+3, right, item.Description, themeStyle("menu.description"))
+	}
+	if cx > 0 {
+		PrintString(screen, left+2, y, "←")
+	}
+}
+
+func (m *choiceMenu) run(screen tcell.Screen) int {
+	nc := m.source.Len() - 1
+	selection := m.def
+	if selection < 0 || selection > nc {
+		selection = 0
+	}
+	selection = m.settle(selection, 1, selection)
+	offset := 0
+	cx := 0
+	var saved *screenRegion
+	var buttons tcell.ButtonMask
+	pv := previewPane{selection: -1}
+	defer func() {
+		if saved != nil {
+			saved.restore(screen)
+			screen.Show()
+		}
+	}()
+	for {
+		// The source may have grown since the last time round.
+		nc = m.source.Len() - 1
+		if nc >= 0 && !m.source.At(selection).Selectable() {
+			selection = m.settle(selection, 1, selection)
+		}
+		title := m.title
+		if m.status != nil {
+			title += m.status()
+		}
+		sx, sy := screen.Size()
+		screen.HideCursor()
+		// The rows of the menu are drawn from top, between columns left
+		// and right; page is how far C-v and M-v move.
+		var left, top, right, rows, page, desccol int
+		if m.popup {
+			if saved != nil {
+				saved.restore(screen)
+			}
+			var bw, bh int
+			desccol, bw, bh = m.measure(offset, sy-2)
+			bx, by, bw, bh := popupRect(sx, sy, bw, bh, m.px, m.py)
+			saved = saveRegion(screen, bx, by, bw, bh)
+			drawBox(screen, bx, by, bw, bh, title, themeStyle("border"))
+			left, top, right, rows = bx+1, by+1, bx+bw-1, bh-2
+			page = rows - 1
+		} else {
+			screen.Clear()
+			PrintString(screen, 0, 0, title)
+			left, top, right, rows = 0, 1, sx, sy-1
+			page = sy - 5
+			if m.preview != nil {
+				pv.layout(sx, sy, &right, &rows, &page)
+			}
+			desccol, _, _ = m.measure(offset, rows)
+		}
+		// Scroll by five rows at a time, unless that's too many to keep
+		// the selection in view.
+		step := 5
+		if step > rows/2 {
+			step = rows / 2
+			if step < 1 {
+				step = 1
+			}
+		}
+		if selection < offset {
+			offset -= step * ((offset - selection + step - 1) / step)
+			if offset < 0 {
+				offset = 0
+			}
+		}
+		if selection-offset >= rows {
+			offset += step * ((selection - offset - rows + step) / step)
+			if offset >= nc {
+				offset = nc
+			}
+		}
+		for i := offset; i <= nc && i-offset < rows; i++ {
+			m.drawItem(screen, i+top-offset, left, right, cx, desccol, m.source.At(i))
+		}
+		PrintString(screen, left+1, selection+top-offset, ">")
+		if m.preview != nil && !m.popup {
+			if pv.selection != selection && nc >= 0 {
+				pv.set(selection, m.preview(selection))
+			}
+			pv.draw(screen)
+		}
+		if m.callback != nil {
+			m.callback(screen, selection, sx, sy)
+		}
+		screen.Show()
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventMouse:
+			if !UseMouse {
+				continue
+			}
+			// Only act when a button goes down, not while it's held.
+			// The wheel sends an event per notch with no release, so
+			// it's left out of buttons and every notch counts.
+			pressed := ev.Buttons() &^ buttons
+			buttons = ev.Buttons() & heldButtons
+			mx, my := ev.Position()
+			inside := mx >= left && mx < right && my >= top && my < top+rows
+			switch {
+			case pressed&tcell.WheelUp != 0 && pv.contains(mx, my):
+				pv.scroll(-3)
+			case pressed&tcell.WheelDown != 0 && pv.contains(mx, my):
+				pv.scroll(3)
+			case pressed&tcell.WheelUp != 0:
+				selection = m.settle(selection-3, -1, selection)
+			case pressed&tcell.WheelDown != 0:
+				selection = m.settle(selection+3, 1, selection)
+			case pressed&tcell.Button1 != 0:
+				if !inside {
+					if m.popup {
+						return m.def
+					}
+					break
+				}
+				i := my - top + offset
+				if i > nc || !m.source.At(i).Selectable() {
+					break
+				}
+				if i == selection {
+					return selection
+				}
+				selection = i
+			}
+		case *tcell.EventKey:
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-v":
+				fallthrough
+			case "next":
+				selection = m.settle(selection+page, 1, selection)
+			case "M-v":
+				fallthrough
+			case "prior":
+				selection = m.settle(selection-page, -1, selection)
+			case "C-c":
+				fallthrough
+			case "C-g":
+				return m.def
+			case "UP", "C-p":
+				if i := m.nextSelectable(selection-1, -1); i >= 0 {
+					selection = i
+				}
+			case "DOWN", "C-n":
+				if i := m.nextSelectable(selection+1, 1); i >= 0 {
+					selection = i
+				}
+			case "LEFT", "C-b":
+				if cx > 0 {
+					cx--
+				}
+			case "RIGHT", "C-f":
+				cx++
+			case "C-a", "Home":
+				cx = 0
+			case "M-p", "M-UP":
+				pv.scroll(-1)
+			case "M-n", "M-DOWN":
+				pv.scroll(1)
+			case "M-<":
+				selection = m.settle(0, 1, selection)
+			case "M->":
+				selection = m.settle(nc, -1, selection)
+			case "RET":
+				if nc >= 0 && m.source.At(selection).Selectable() {
+					return selection
+				}
+			default:
+				if i, unique := m.findAccelerator(key, selection, offset, rows); unique {
+					return i
+				} else if i >= 0 {
+					selection = i
+				}
+			}
+		}
+	}
+}
+
+// findAccelerator looks for selectable entries with the accelerator key,
+// starting after the entry at from and wrapping around. Returns the first one
+// found (or -1), and whether it's the only one. Sources that aren't slices may
+// be huge, so only their rows entries from offset on are searched.
+func (m *choiceMenu) findAccelerator(key string, from, offset, rows int) (int, bool) {
+	switch s := m.source.(type) {
+	case StringSource:
+		return -1, false
+	case ItemSource:
+		if m.keys == nil {
+			m.keys = make(map[string][]int)
+			for i, item := range s {
+				if item.Key != "" && item.Selectable() {
+					k := strings.ToLower(item.Key)
+					m.keys[k] = append(m.keys[k], i)
+				}
+			}
+		}
+		found := m.keys[strings.ToLower(key)]
+		if len(found) == 0 {
+			return -1, false
+		}
+		i := sort.SearchInts(found, from+1)
+		if i == len(found) {
+			i = 0
+		}
+		return found[i], len(found) == 1
+	}
+	found := -1
+	n := m.source.Len() - offset
+	if n > rows {
+		n = rows
+	}
+	if from < offset || from >= offset+n {
+		from = offset - 1
+	}
+	for j := 1; j <= n; j++ {
+		i := offset + (from-offset+j)%n
+		item := m.source.At(i)
+		if item.Key == "" || !item.Selectable() || !strings.EqualFold(item.Key, key) {
+			continue
+		}
+		if found >= 0 {
+			return found, false
+		}
+		found = i
+	}
+	return found, found >= 0
+}
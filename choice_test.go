@@ -0,0 +1,46 @@
+package termutil
+
+import "testing"
+
+func TestParseAccelerator(t *testing.T) {
+	tests := []struct {
+		in, label, key string
+		at             int
+	}{
+		{"Save", "Save", "", -1},
+		{"&Save", "Save", "s", 0},
+		{"Save &as", "Save as", "a", 5},
+		{"Fish && &Chips", "Fish & Chips", "c", 7},
+		{"&&Co", "&Co", "", -1},
+		{"Trailing &", "Trailing &", "", -1},
+		{"&One &Two", "One Two", "o", 0},
+		{"Caf&é", "Café", "é", 3},
+		{"", "", "", -1},
+	}
+	for _, tt := range tests {
+		label, key, at := parseAccelerator(tt.in)
+		if label != tt.label || key != tt.key || at != tt.at {
+			t.Errorf("parseAccelerator(%q) = %q, %q, %d, want %q, %q, %d",
+				tt.in, label, key, at, tt.label, tt.key, tt.at)
+		}
+	}
+}
+
+func TestAcceleratorIndex(t *testing.T) {
+	tests := []struct {
+		label, key string
+		want       int
+	}{
+		{"Open", "o", 0},
+		{"Save as", "A", 1},
+		{"Quit", "x", -1},
+		{"ȺȺx", "x", 4},
+		{"Ⱥbc", "ⱥ", 0},
+		{"Open", "op", -1},
+	}
+	for _, tt := range tests {
+		if got := acceleratorIndex(tt.label, tt.key); got != tt.want {
+			t.Errorf("acceleratorIndex(%q, %q) = %d, want %d", tt.label, tt.key, got, tt.want)
+		}
+	}
+}
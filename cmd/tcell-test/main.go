@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	termutil "github.com/japanoise/tcell-util"
@@ -18,6 +20,10 @@ func main() {
 		log.Fatalf("%+v", err)
 	}
 
+	// Let the menus use the mouse
+	s.EnableMouse()
+	termutil.UseMouse = true
+
 	// Set default text style
 	s.SetStyle(tcell.StyleDefault)
 
@@ -31,12 +37,24 @@ func main() {
 	testScroll := "Scrolling through text"
 	testKey := "Prompting for characters"
 	testColor := "Selecting colors"
+	testItems := "Menu with descriptions"
+	testPopup := "Pop-up menu"
+	testAccel := "Menu with accelerators"
+	testDialog := "Dialog box"
+	testRGB := "Selecting 24-bit colors"
+	testStream := "Scrolling through text as it arrives"
 	quit := "Quit"
 	choices := []string{
 		testPrompt,
 		testScroll,
 		testKey,
 		testColor,
+		testItems,
+		testPopup,
+		testAccel,
+		testDialog,
+		testRGB,
+		testStream,
 		quit,
 	}
 	text := []string{
@@ -127,6 +145,44 @@ func main() {
 			}
 		case testColor:
 			color = termutil.PickColor(s, "Pick a color!")
+		case testItems:
+			termutil.ChoiceItemIndex(s, "Pick a command", []termutil.ChoiceItem{
+				{Label: "File", Header: true},
+				{Label: "Open", Description: "C-x C-f"},
+				{Label: "Save", Description: "C-x C-s"},
+				{Label: "Revert", Description: "not modified", Disabled: true},
+				{Separator: true},
+				{Label: "Edit", Header: true},
+				{Label: "Undo", Description: "C-_"},
+				{Label: "Kill ring", Description: "M-y",
+					Style: tcell.StyleDefault.Foreground(color)},
+			}, 0)
+		case testAccel:
+			termutil.ChoiceIndexAccel(s, "Press a key",
+				[]string{"&Open", "&Save", "Save &as", "Revert", "Close"}, 0)
+		case testRGB:
+			color = termutil.PickColorRGB(s, "Pick a color!", color)
+		case testStream:
+			r, w := io.Pipe()
+			go func() {
+				for i := 1; i <= 200; i++ {
+					if _, err := fmt.Fprintf(w, "Line %d of 200. Press F to follow.\n", i); err != nil {
+						return
+					}
+					time.Sleep(50 * time.Millisecond)
+				}
+				w.Close()
+			}()
+			termutil.DisplayScreenReader(s, "Counting", r)
+		case testDialog:
+			termutil.Dialog(s, "Quit",
+				"You have unsaved changes. Do you want to save them before quitting?",
+				"&Save", "&Discard", "&Cancel")
+		case testPopup:
+			if termutil.YesNoPopup(s, "Show a pop-up menu?") {
+				termutil.ChoiceIndexPopupAt(s, 20, 3, "Fruit",
+					[]string{"Apple", "Banana", "Cherry"}, 0)
+			}
 		case quit:
 			return
 		}
@@ -0,0 +1,369 @@
+package termutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Converts a colour from hue (in degrees), saturation and value (from 0 to 1)
+// to red, green and blue (from 0 to 255).
+func hsvToRGB(h, s, v float64) (int32, int32, int32) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return int32(math.Round((r + m) * 255)), int32(math.Round((g + m) * 255)), int32(math.Round((b + m) * 255))
+}
+
+// Converts a colour from red, green and blue (from 0 to 255) to hue (in
+// degrees), saturation and value (from 0 to 1).
+func rgbToHSV(r, g, b int32) (float64, float64, float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	d := max - min
+	var h float64
+	switch {
+	case d == 0:
+		h = 0
+	case max == rf:
+		h = 60 * math.Mod((gf-bf)/d, 6)
+	case max == gf:
+		h = 60 * ((bf-rf)/d + 2)
+	default:
+		h = 60 * ((rf-gf)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	s := 0.0
+	if max > 0 {
+		s = d / max
+	}
+	return h, s, max
+}
+
+// Parses a colour written as "#rrggbb" or "#rgb" (the # is optional). Returns
+// tcell.ColorDefault and false if s isn't one.
+func parseHexColor(s string) (tcell.Color, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return tcell.ColorDefault, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return tcell.ColorDefault, false
+	}
+	return tcell.NewHexColor(int32(v)), true
+}
+
+// Parses a colour given as a hex code (see parseHexColor), one of the names in
+// tcell.ColorNames, or a palette entry written as "color" and its number, from
+// color0 to color255.
+func parseColor(s string) (tcell.Color, bool) {
+	if c, ok := parseHexColor(s); ok {
+		return c, true
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "color") {
+		if n, err := strconv.Atoi(s[len("color"):]); err == nil && n >= 0 && n < 256 {
+			return tcell.PaletteColor(n), true
+		}
+	}
+	c := tcell.GetColor(s)
+	return c, c != tcell.ColorDefault
+}
+
+// Names of the first 16 palette colours.
+var paletteNames = []string{
+	"black", "maroon", "green", "olive", "navy", "purple", "teal", "silver",
+	"gray", "red", "lime", "yellow", "blue", "fuchsia", "aqua", "white",
+}
+
+// Returns how many palette colours the screen can show, from 8 to 256.
+func paletteSize(screen tcell.Screen) int {
+	n := screen.Colors()
+	if n > 256 {
+		return 256
+	}
+	if n < 8 {
+		return 8
+	}
+	return n
+}
+
+// Returns the index of the palette colour nearest to c, out of the first n.
+func paletteIndex(c tcell.Color, n int) int {
+	if !c.IsRGB() && c.Valid() && int(c-tcell.ColorBlack) < n {
+		return int(c - tcell.ColorBlack)
+	}
+	palette := make([]tcell.Color, n)
+	for i := range palette {
+		palette[i] = tcell.ColorBlack + tcell.Color(i)
+	}
+	return int(tcell.FindColor(c, palette) - tcell.ColorBlack)
+}
+
+// Describes palette colour idx on row y, along with how it'll look on screens
+// with fewer than ncolors colours.
+func describePaletteColor(screen tcell.Screen, y, idx, ncolors int) {
+	c := tcell.ColorBlack + tcell.Color(idx)
+	desc := fmt.Sprintf("Colour %d %s", idx, hexColor(c))
+	if idx < 16 {
+		desc += " " + paletteNames[idx]
+	}
+	x := printStringClip(screen, 0, y, 0, 1<<30, desc, tcell.StyleDefault)
+	for _, n := range []int{16, 8} {
+		if n >= ncolors {
+			continue
+		}
+		i := paletteIndex(c, n)
+		x = printStringClip(screen, x+3, y, 0, 1<<30, fmt.Sprintf("%d colours: ", n), themeStyle("hint"))
+		for j := 0; j < 2; j++ {
+			screen.SetContent(x+j, y, ' ', nil, tcell.StyleDefault.Background(tcell.ColorBlack+tcell.Color(i)))
+		}
+		x = printStringClip(screen, x+3, y, 0, 1<<30, paletteNames[i], themeStyle("hint"))
+	}
+}
+
+// Returns the colour written as "#rrggbb".
+func hexColor(c tcell.Color) string {
+	r, g, b := c.RGB()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// Returns black or white, whichever shows up better against c.
+func contrastColor(c tcell.Color) tcell.Color {
+	r, g, b := c.RGB()
+	if r*299+g*587+b*114 > 128000 {
+		return tcell.ColorBlack
+	}
+	return tcell.ColorWhite
+}
+
+// Allows the user to pick any 24-bit colour. A plane of saturation (across)
+// against value (down) is shown for the current hue, with a hue slider below
+// it. The arrow keys (or C-f, C-b, C-n and C-p) move around the plane; M-LEFT
+// and M-RIGHT (or M-b and M-f) change the hue, # lets the user type in a hex
+// code or name, and TAB picks from the named colours. Returns the colour made
+// with tcell.NewRGBColor, or def if the user cancelled. If the screen can't show
+// 24-bit colour, this falls back on PickColor, starting at the palette colour
+// nearest def; C-g still returns def.
+func PickColorRGB(screen tcell.Screen, prompt string, def tcell.Color) tcell.Color {
+	if screen.Colors() < 1<<24 {
+		return pickColor(screen, prompt, def, true)
+	}
+	h, s, v := 0.0, 1.0, 1.0
+	if def.Valid() {
+		h, s, v = rgbToHSV(def.RGB())
+	}
+	for {
+		sx, sy := screen.Size()
+		// The plane, leaving room for the swatch on the right and the
+		// hue slider and help underneath.
+		pw, ph := sx-16, sy-5
+		if pw > 72 {
+			pw = 72
+		}
+		if pw < 2 {
+			pw = 2
+		}
+		if ph < 2 {
+			ph = 2
+		}
+		hw := pw
+		color := tcell.NewRGBColor(hsvToRGB(h, s, v))
+
+		screen.Clear()
+		PrintString(screen, 0, 0, prompt)
+		for j := 0; j < ph; j++ {
+			for i := 0; i < pw; i++ {
+				c := tcell.NewRGBColor(hsvToRGB(h, float64(i)/float64(pw-1), 1-float64(j)/float64(ph-1)))
+				screen.SetContent(i, 1+j, ' ', nil, tcell.StyleDefault.Background(c))
+			}
+		}
+		cx := int(math.Round(s * float64(pw-1)))
+		cy := 1 + int(math.Round((1-v)*float64(ph-1)))
+		screen.SetContent(cx, cy, '+', nil, tcell.StyleDefault.Background(color).Foreground(contrastColor(color)))
+		screen.ShowCursor(cx, cy)
+
+		hy := 2 + ph
+		for i := 0; i < hw; i++ {
+			c := tcell.NewRGBColor(hsvToRGB(float64(i)*360/float64(hw), 1, 1))
+			screen.SetContent(i, hy, ' ', nil, tcell.StyleDefault.Background(c))
+		}
+		hx := int(math.Round(h*float64(hw)/360)) % hw
+		screen.SetContent(hx, hy, '^', nil, tcell.StyleDefault.Background(tcell.NewRGBColor(hsvToRGB(h, 1, 1))).Foreground(tcell.ColorBlack))
+
+		// The swatch and its description.
+		wx := pw + 2
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 12; i++ {
+				screen.SetContent(wx+i, 1+j, ' ', nil, tcell.StyleDefault.Background(color))
+			}
+		}
+		PrintString(screen, wx, 6, hexColor(color))
+		PrintString(screen, wx, 7, fmt.Sprintf("H %3.0f", h))
+		PrintString(screen, wx, 8, fmt.Sprintf("S %3.0f%%", s*100))
+		PrintString(screen, wx, 9, fmt.Sprintf("V %3.0f%%", v*100))
+		PrintStringStyle(screen, 0, sy-1, "Arrows: sat/val  M-LEFT/M-RIGHT: hue  #: hex  TAB: names  RET: ok", themeStyle("help"))
+		screen.Show()
+
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			sstep := 1 / float64(pw-1)
+			vstep := 1 / float64(ph-1)
+			hstep := 360 / float64(hw)
+			switch ParseTcellEvent(ev) {
+			case "C-c", "C-g":
+				return def
+			case "RET":
+				return color
+			case "LEFT", "C-b":
+				s -= sstep
+			case "RIGHT", "C-f":
+				s += sstep
+			case "UP", "C-p":
+				v += vstep
+			case "DOWN", "C-n":
+				v -= vstep
+			case "M-LEFT", "M-b":
+				h -= hstep
+			case "M-RIGHT", "M-f":
+				h += hstep
+			case "#":
+				text := Edit(screen, hexColor(color), "Colour (#rrggbb or name)", nil)
+				if c, ok := parseColor(text); ok {
+					h, s, v = rgbToHSV(c.RGB())
+				}
+			case "TAB":
+				if c, ok := pickNamedColor(screen, prompt); ok {
+					h, s, v = rgbToHSV(c.RGB())
+				}
+			}
+			s = math.Max(0, math.Min(1, s))
+			v = math.Max(0, math.Min(1, v))
+			h = math.Mod(h+360, 360)
+		}
+	}
+}
+
+// Allows the user to pick one of tcell's named colours from a list, which is
+// narrowed down by typing part of a name. Returns false if the user cancelled.
+func pickNamedColor(screen tcell.Screen, prompt string) (tcell.Color, bool) {
+	names := make([]string, 0, len(tcell.ColorNames))
+	for name := range tcell.ColorNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	filter := ""
+	selection := 0
+	offset := 0
+	for {
+		matches := make([]string, 0, len(names))
+		for _, name := range names {
+			if strings.Contains(name, strings.ToLower(filter)) {
+				matches = append(matches, name)
+			}
+		}
+		nm := len(matches) - 1
+		if selection > nm {
+			selection = nm
+		}
+		if selection < 0 {
+			selection = 0
+		}
+
+		sx, sy := screen.Size()
+		rows := sy - 2
+		if selection < offset {
+			offset = selection
+		} else if selection >= offset+rows {
+			offset = selection - rows + 1
+		}
+		screen.Clear()
+		PrintString(screen, 0, 0, prompt)
+		for i := offset; i <= nm && i-offset < rows; i++ {
+			c := tcell.ColorNames[matches[i]]
+			y := 1 + i - offset
+			for j := 0; j < 6; j++ {
+				screen.SetContent(3+j, y, ' ', nil, tcell.StyleDefault.Background(c))
+			}
+			printStringClip(screen, 10, y, 10, sx, matches[i], tcell.StyleDefault)
+			printStringClip(screen, 12+RunewidthStr(matches[i]), y, 10, sx, hexColor(c), themeStyle("hint"))
+		}
+		if nm >= 0 {
+			PrintString(screen, 1, 1+selection-offset, ">")
+		}
+		ClearLine(screen, sx, sy-1)
+		PrintString(screen, 0, sy-1, "Filter: "+filter)
+		screen.ShowCursor(8+RunewidthStr(filter), sy-1)
+		screen.Show()
+
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-c", "C-g", "TAB":
+				return tcell.ColorDefault, false
+			case "RET":
+				if nm >= 0 {
+					return tcell.ColorNames[matches[selection]], true
+				}
+			case "UP", "C-p":
+				selection--
+			case "DOWN", "C-n":
+				selection++
+			case "C-v", "next":
+				selection += rows - 1
+			case "M-v", "prior":
+				selection -= rows - 1
+			case "M-<":
+				selection = 0
+			case "M->":
+				selection = nm
+			case "DEL", "C-h":
+				if filter != "" {
+					_, size := utf8.DecodeLastRuneInString(filter)
+					filter = filter[:len(filter)-size]
+				}
+			case "C-u":
+				filter = ""
+			default:
+				if utf8.RuneCountInString(key) == 1 {
+					filter += key
+					selection = 0
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package termutil
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Widest a dialog's message is allowed to get before it's wrapped.
+const dialogMaxWidth = 60
+
+type dialogButton struct {
+	label, key string
+	// at is the byte offset of the accelerator in label, or -1.
+	at int
+	// x is where the button was last drawn.
+	x int
+}
+
+func (b dialogButton) text() string {
+	return "[ " + b.label + " ]"
+}
+
+// Returns the byte offset of the accelerator in the label, or -1.
+func (b dialogButton) accelerator() int {
+	if b.at >= 0 {
+		return b.at
+	}
+	return acceleratorIndex(b.label, b.key)
+}
+
+// Shows a dialog box in the middle of the screen with a title, a message, and a
+// row of buttons, e.g. Dialog(screen, "Quit", "Save changes?", "&Yes", "&No",
+// "&Cancel"). The message is word-wrapped to fit. TAB and the arrow keys move
+// between the buttons and RET presses the focused one; a button can also be
+// pressed with its accelerator, marked with an ampersand as for
+// AcceleratorItems (or else its first letter), or by clicking it if UseMouse is
+// set. Returns the index of the button pressed, or -1 if the user pressed C-g.
+// The screen is put back as it was when the dialog closes.
+func Dialog(screen tcell.Screen, title, message string, buttons ...string) int {
+	return DialogDefault(screen, title, message, 0, buttons...)
+}
+
+// As Dialog, but button number def has the focus to begin with.
+func DialogDefault(screen tcell.Screen, title, message string, def int, buttons ...string) int {
+	btns := make([]dialogButton, len(buttons))
+	used := make(map[string]bool)
+	for i, b := range buttons {
+		btns[i].label, btns[i].key, btns[i].at = parseAccelerator(b)
+		if btns[i].key == "" && btns[i].label != "" {
+			// Fall back on the first letter, if nobody else has it.
+			r, _ := utf8.DecodeRuneInString(btns[i].label)
+			if k := strings.ToLower(string(r)); !used[k] {
+				btns[i].key = k
+			}
+		}
+		used[btns[i].key] = true
+	}
+	focus := def
+	if focus < 0 || focus >= len(btns) {
+		focus = 0
+	}
+	var saved *screenRegion
+	var mousebuttons tcell.ButtonMask
+	var by int
+	defer func() {
+		if saved != nil {
+			saved.restore(screen)
+			screen.Show()
+		}
+	}()
+	for {
+		if saved != nil {
+			saved.restore(screen)
+		}
+		sx, sy := screen.Size()
+		btnw := 0
+		for _, b := range btns {
+			btnw += RunewidthStr(b.text()) + 1
+		}
+		w := RunewidthStr(title) + 6
+		if btnw+3 > w {
+			w = btnw + 3
+		}
+		msgw := RunewidthStr(message)
+		if msgw > dialogMaxWidth {
+			msgw = dialogMaxWidth
+		}
+		if msgw+4 > w {
+			w = msgw + 4
+		}
+		if w > sx {
+			w = sx
+		}
+		lines := wrapText(message, w-4)
+		x, y, w, h := popupRect(sx, sy, w, len(lines)+4, -1, -1)
+		saved = saveRegion(screen, x, y, w, h)
+		drawBox(screen, x, y, w, h, title, themeStyle("border"))
+		for i, line := range lines {
+			if i >= h-4 {
+				break
+			}
+			printStringClip(screen, x+2, y+1+i, x+1, x+w-1, line, tcell.StyleDefault)
+		}
+
+		// Centre the buttons on the bottom row inside the box.
+		by = y + h - 2
+		bx := x + (w-btnw)/2 + 1
+		screen.HideCursor()
+		for i := range btns {
+			b := &btns[i]
+			b.x = bx
+			style := themeStyle("button")
+			if i == focus {
+				style = themeStyle("button.focus")
+				screen.ShowCursor(bx+2, by)
+			}
+			bx = printStringClip(screen, bx, by, x+1, x+w-1, "[ ", style)
+			bx = printAccelerated(screen, bx, by, x+1, x+w-1, b.label, b.accelerator(), style)
+			bx = printStringClip(screen, bx, by, x+1, x+w-1, " ]", style) + 1
+		}
+		screen.Show()
+
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventMouse:
+			if !UseMouse {
+				continue
+			}
+			pressed := ev.Buttons() &^ mousebuttons
+			mousebuttons = ev.Buttons()
+			mx, my := ev.Position()
+			if pressed&tcell.Button1 == 0 || my != by {
+				continue
+			}
+			for i, b := range btns {
+				if mx >= b.x && mx < b.x+RunewidthStr(b.text()) {
+					return i
+				}
+			}
+		case *tcell.EventKey:
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-g", "C-c", "ESC":
+				return -1
+			case "TAB", "RIGHT", "C-f", "DOWN", "C-n":
+				if len(btns) > 0 {
+					focus = (focus + 1) % len(btns)
+				}
+			case "BACKTAB", "LEFT", "C-b", "UP", "C-p":
+				if len(btns) > 0 {
+					focus = (focus + len(btns) - 1) % len(btns)
+				}
+			case "RET", " ":
+				if len(btns) > 0 {
+					return focus
+				}
+			default:
+				for i, b := range btns {
+					if b.key != "" && strings.EqualFold(b.key, key) {
+						return i
+					}
+				}
+			}
+		}
+	}
+}
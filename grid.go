@@ -0,0 +1,152 @@
+package termutil
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Allows the user to select one of many short choices (e.g. emoji or colour
+// names), laid out in as many columns as will fit on the screen. The arrow keys
+// (or C-f, C-b, C-n and C-p) move around the grid, and C-v and M-v move by a
+// screenful of rows. Returns an index into the choices array; or def (default)
+func ChoiceGrid(screen tcell.Screen, title string, choices []string, def int) int {
+	return ChoiceGridCallback(screen, title, choices, def, nil)
+}
+
+// As ChoiceGrid, but calls a function after drawing the interface, passing it
+// the current selected choice, screen width, and screen height.
+func ChoiceGridCallback(screen tcell.Screen, title string, choices []string, def int, f func(tcell.Screen, int, int, int)) int {
+	selection := def
+	nc := len(choices) - 1
+	if selection < 0 || selection > nc {
+		selection = 0
+	}
+	colw := 1
+	for _, s := range choices {
+		if w := RunewidthStr(s); w > colw {
+			colw = w
+		}
+	}
+	colw += 2
+	offset := 0
+	var buttons tcell.ButtonMask
+	for {
+		sx, sy := screen.Size()
+		ncols := (sx - 1) / colw
+		if ncols < 1 {
+			ncols = 1
+		}
+		rows := sy - 1
+		if rows < 1 {
+			rows = 1
+		}
+		// offset is the first row shown.
+		row := selection / ncols
+		if row < offset {
+			offset = row
+		} else if row >= offset+rows {
+			offset = row - rows + 1
+		}
+
+		screen.Clear()
+		PrintString(screen, 0, 0, title)
+		for i := offset * ncols; i <= nc && i < (offset+rows)*ncols; i++ {
+			x := 1 + (i%ncols)*colw
+			y := 1 + i/ncols - offset
+			style := tcell.StyleDefault
+			if i == selection {
+				style = themeStyle("selection")
+				screen.ShowCursor(x, y)
+			}
+			printStringClip(screen, x, y, 0, sx, choices[i], style)
+		}
+		if nc < 0 {
+			screen.HideCursor()
+		}
+		if f != nil {
+			f(screen, selection, sx, sy)
+		}
+		screen.Show()
+
+		lastrow := nc / ncols
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventMouse:
+			if !UseMouse {
+				continue
+			}
+			// As in choiceMenu.run, every notch of the wheel counts.
+			pressed := ev.Buttons() &^ buttons
+			buttons = ev.Buttons() & heldButtons
+			mx, my := ev.Position()
+			switch {
+			case pressed&tcell.WheelUp != 0:
+				if selection >= ncols {
+					selection -= ncols
+				}
+			case pressed&tcell.WheelDown != 0:
+				if selection+ncols <= nc {
+					selection += ncols
+				}
+			case pressed&tcell.Button1 != 0:
+				col := (mx - 1) / colw
+				i := (my-1+offset)*ncols + col
+				if mx < 1 || my < 1 || col >= ncols || i > nc {
+					break
+				}
+				if i == selection {
+					return selection
+				}
+				selection = i
+			}
+		case *tcell.EventKey:
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-c", "C-g":
+				return def
+			case "LEFT", "C-b":
+				if selection > 0 {
+					selection--
+				}
+			case "RIGHT", "C-f":
+				if selection < nc {
+					selection++
+				}
+			case "UP", "C-p":
+				if selection >= ncols {
+					selection -= ncols
+				}
+			case "DOWN", "C-n":
+				if selection+ncols <= nc {
+					selection += ncols
+				} else if selection/ncols < lastrow {
+					selection = nc
+				}
+			case "C-v", "next":
+				selection += rows * ncols
+				if selection > nc {
+					selection = nc
+				}
+			case "M-v", "prior":
+				selection -= rows * ncols
+				if selection < 0 {
+					selection = 0
+				}
+			case "C-a", "Home":
+				selection -= selection % ncols
+			case "C-e", "End":
+				selection += ncols - 1 - selection%ncols
+				if selection > nc {
+					selection = nc
+				}
+			case "M-<":
+				selection = 0
+			case "M->":
+				selection = nc
+			case "RET":
+				if nc >= 0 {
+					return selection
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,10 @@ package termutil
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
@@ -255,150 +259,181 @@ func ChoiceIndex(screen tcell.Screen, title string, choices []string, def int) i
 //As ChoiceIndex, but calls a function after drawing the interface,
 //passing it the current selected choice, screen width, and screen height.
 func ChoiceIndexCallback(screen tcell.Screen, title string, choices []string, def int, f func(tcell.Screen, int, int, int)) int {
-	selection := def
-	nc := len(choices) - 1
-	if selection < 0 || selection > nc {
-		selection = 0
-	}
-	offset := 0
-	cx := 0
-	for {
-		sx, sy := screen.Size()
-		screen.HideCursor()
-		screen.Clear()
-		PrintString(screen, 0, 0, title)
-		for selection < offset {
-			offset -= 5
-			if offset < 0 {
-				offset = 0
-			}
-		}
-		for selection-offset >= sy-1 {
-			offset += 5
-			if offset >= nc {
-				offset = nc
-			}
-		}
-		for i, s := range choices[offset:] {
-			ts, _ := trimString(s, cx)
-			PrintString(screen, 3, i+1, ts)
-			if cx > 0 {
-				PrintString(screen, 2, i+1, "←")
-			}
-		}
-		PrintString(screen, 1, (selection+1)-offset, ">")
-		if f != nil {
-			f(screen, selection, sx, sy)
-		}
-		screen.Show()
-		ev := screen.PollEvent()
-		switch ev := ev.(type) {
-		case *tcell.EventKey:
-			key := ParseTcellEvent(ev)
-			switch key {
-			case "C-v":
-				fallthrough
-			case "next":
-				selection += sy - 5
-				if selection >= len(choices) {
-					selection = len(choices) - 1
-				}
-			case "M-v":
-				fallthrough
-			case "prior":
-				selection -= sy - 5
-				if selection < 0 {
-					selection = 0
-				}
-			case "C-c":
-				fallthrough
-			case "C-g":
-				return def
-			case "UP", "C-p":
-				if selection > 0 {
-					selection--
-				}
-			case "DOWN", "C-n":
-				if selection < len(choices)-1 {
-					selection++
-				}
-			case "LEFT", "C-b":
-				if cx > 0 {
-					cx--
-				}
-			case "RIGHT", "C-f":
-				cx++
-			case "C-a", "Home":
-				cx = 0
-			case "M-<":
-				selection = 0
-			case "M->":
-				selection = len(choices) - 1
-			case "RET":
-				return selection
-			}
-		}
-	}
+	return ChoiceSourceIndexCallback(screen, title, StringSource(choices), def, f)
+}
+
+// As ChoiceIndex, but each choice gets an accelerator key, as described for
+// AcceleratorItems. Pressing an accelerator selects and accepts its choice;
+// if more than one choice has the same key, it moves between them instead.
+func ChoiceIndexAccel(screen tcell.Screen, title string, choices []string, def int) int {
+	return ChoiceItemIndex(screen, title, AcceleratorItems(choices), def)
 }
 
 //Displays the prompt p and asks the user to say y or n. Returns true if y; false
 //if no.
 func YesNo(screen tcell.Screen, p string, refresh func(tcell.Screen, int, int)) bool {
-	ret, _ := yesNoChoice(screen, p, false, refresh)
+	ret, _ := yesNoChoice(func(keys ...string) string {
+		return PressKey(screen, p, refresh, keys...)
+	}, false)
 	return ret
 }
 
 //Same as YesNo, but will return a non-nil error if the user presses C-g.
 func YesNoCancel(screen tcell.Screen, p string, refresh func(tcell.Screen, int, int)) (bool, error) {
-	return yesNoChoice(screen, p, true, refresh)
+	return yesNoChoice(func(keys ...string) string {
+		return PressKey(screen, p, refresh, keys...)
+	}, true)
 }
 
 // Asks the user to press one of a set of keys. Returns the one which they pressed.
+// A key may be a sequence of keys separated by spaces, e.g. "C-x C-s"; the keys
+// pressed so far are shown after the prompt, as Emacs does. C-g, or a key which
+// doesn't continue any of the sequences, abandons the sequence.
 func PressKey(screen tcell.Screen, p string, refresh func(tcell.Screen, int, int), keys ...string) string {
-	var plen int
-	pm := p + " ("
-	for i, key := range keys {
-		if i != 0 {
-			pm += "/"
+	return pressKey(screen, pressKeyPrompt(p, keys), refresh, 0, keys)
+}
+
+// pressKeyTick is posted to wake pressKey once a second while it counts down,
+// and once more at the deadline.
+type pressKeyTick struct{}
+
+// Shows the prompt pm and waits for one of keys to be pressed. If timeout is
+// positive, the seconds remaining are shown after the prompt, and "" is
+// returned if they run out.
+func pressKey(screen tcell.Screen, pm string, refresh func(tcell.Screen, int, int), timeout time.Duration, keys []string) string {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+		var mu sync.Mutex
+		done := make(chan struct{})
+		// Holding mu while posting means no tick can reach whatever reads
+		// the screen's events after pressKey returns.
+		tick := func() {
+			mu.Lock()
+			defer mu.Unlock()
+			select {
+			case <-done:
+			default:
+				screen.PostEvent(tcell.NewEventInterrupt(pressKeyTick{}))
+			}
 		}
-		pm += key
+		defer func() {
+			mu.Lock()
+			close(done)
+			mu.Unlock()
+		}()
+		final := time.AfterFunc(timeout, tick)
+		defer final.Stop()
+		go func() {
+			// The ticker only keeps the countdown up to date.
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					tick()
+				}
+			}
+		}()
 	}
-	pm += ")"
-	plen = utf8.RuneCountInString(pm) + 1
-	x, y := screen.Size()
-	if refresh != nil {
-		refresh(screen, x, y)
+	seq := keySequence{keys: keys}
+	draw := func() {
+		msg := pm
+		if timeout > 0 {
+			left := time.Until(deadline).Round(time.Second)
+			msg += fmt.Sprintf(" [%ds]", left/time.Second)
+		}
+		msg += seq.String()
+		x, y := screen.Size()
+		if refresh != nil {
+			refresh(screen, x, y)
+		}
+		ClearLine(screen, x, y-1)
+		PrintString(screen, 0, y-1, msg)
+		screen.ShowCursor(utf8.RuneCountInString(msg)+1, y-1)
+		screen.Show()
 	}
-	ClearLine(screen, x, y-1)
-	PrintString(screen, 0, y-1, pm)
-	screen.ShowCursor(plen, y-1)
-	screen.Show()
+	draw()
 	for {
 		ev := screen.PollEvent()
 		switch ev := ev.(type) {
 		case *tcell.EventResize:
-			x, y = screen.Size()
-			if refresh != nil {
-				refresh(screen, x, y)
+			draw()
+		case *tcell.EventInterrupt:
+			// Other interrupts, such as from a MessageArea, just redraw.
+			if _, ok := ev.Data().(pressKeyTick); ok && timeout > 0 && !time.Now().Before(deadline) {
+				return ""
 			}
-			ClearLine(screen, x, y-1)
-			PrintString(screen, 0, y-1, pm)
-			screen.ShowCursor(plen, y-1)
-			screen.Show()
+			draw()
 		case *tcell.EventKey:
-			pressedkey := ParseTcellEvent(ev)
-			for _, key := range keys {
-				if key == pressedkey {
-					return key
-				}
+			if key, ok := seq.press(screen, ParseTcellEvent(ev)); ok {
+				return key
 			}
+			draw()
 		}
 	}
 }
 
-func yesNoChoice(screen tcell.Screen, p string, allowcancel bool, refresh func(tcell.Screen, int, int)) (bool, error) {
+// keySequence matches keys pressed one at a time against a set of keys, some
+// of which may be sequences separated by spaces, e.g. "C-x C-s".
+type keySequence struct {
+	keys []string
+	// pending holds the keys pressed so far of a multi-key sequence.
+	pending []string
+}
+
+// Takes the next key pressed, and returns the key it completes, if any. C-g,
+// or a key which doesn't continue any of the sequences, abandons the sequence.
+func (k *keySequence) press(screen tcell.Screen, pressedkey string) (string, bool) {
+	if len(k.pending) > 0 && pressedkey == "C-g" {
+		k.pending = nil
+		return "", false
+	}
+	seq := strings.Join(append(k.pending, pressedkey), " ")
+	prefix := false
+	for _, key := range k.keys {
+		if key == seq {
+			k.pending = nil
+			return key, true
+		}
+		prefix = prefix || strings.HasPrefix(key, seq+" ")
+	}
+	if prefix {
+		k.pending = append(k.pending, pressedkey)
+	} else if len(k.pending) > 0 {
+		// No sequence goes this way; start again.
+		k.pending = nil
+		screen.Beep()
+	}
+	return "", false
+}
+
+// Returns the keys pressed so far of a sequence, to be shown after the prompt,
+// e.g. " C-x-", or "" if there aren't any.
+func (k *keySequence) String() string {
+	if len(k.pending) == 0 {
+		return ""
+	}
+	return " " + strings.Join(k.pending, " ") + "-"
+}
+
+// Returns the prompt followed by the allowed keys, e.g. "Save? (y/n)"
+func pressKeyPrompt(p string, keys []string) string {
+	pm := p + " ("
+	for i, key := range keys {
+		if i != 0 {
+			pm += "/"
+		}
+		pm += key
+	}
+	return pm + ")"
+}
+
+func yesNoChoice(press func(...string) string, allowcancel bool) (bool, error) {
 	if allowcancel {
-		key := PressKey(screen, p, refresh, "y", "n", "C-g")
+		key := press("y", "n", "C-g")
 		switch key {
 		case "y":
 			return true, nil
@@ -408,43 +443,70 @@ func yesNoChoice(screen tcell.Screen, p string, allowcancel bool, refresh func(t
 			return false, errors.New("User cancelled")
 		}
 	}
-	key := PressKey(screen, p, refresh, "y", "n")
+	key := press("y", "n")
 	return key == "y", nil
 }
 
+// Allows the user to pick one of the palette colours from a grid. The grid
+// has as many colours as the screen can show, up to 256; with 16 or fewer,
+// each is labelled with its name. Below the grid, the selected colour is
+// described, along with the colours it'll be shown as on screens with fewer.
+// TAB switches to a list of named colours, and # prompts for a colour's name or
+// hex code. If the screen can show 24-bit colour, a colour chosen by name or
+// code is returned as it is; otherwise, the nearest palette colour is selected.
 func PickColor(screen tcell.Screen, prompt string) tcell.Color {
+	return pickColor(screen, prompt, tcell.ColorDefault, false)
+}
+
+// Does the work of PickColor, starting on the palette colour nearest def unless
+// it's the default colour. If cancellable is set, C-g and C-c return def.
+func pickColor(screen tcell.Screen, prompt string, def tcell.Color, cancellable bool) tcell.Color {
 	idx := 0
+	ncolors := paletteSize(screen)
+	if def != tcell.ColorDefault {
+		idx = paletteIndex(def, ncolors)
+	}
+	cols := 16
+	if ncolors < cols {
+		cols = ncolors
+	}
+	rows := (ncolors + cols - 1) / cols
+	last := ncolors - 1
 	for {
 		sx, sy := screen.Size()
-		pillWidth := sx / 16
+		pillWidth := sx / cols
 		screen.Clear()
 		PrintString(screen, 0, 0, prompt)
-		if sy < 16 {
+		if sy < rows+3 {
 			PrintString(screen, sx-26, 0, "Warning: Screen too short")
 		}
-		if sx < 16 {
+		if sx < cols {
 			PrintString(screen, sx-27, 0, "Warning: Screen too narrow")
 		}
-		for i := 0; i < 256; i++ {
+		for i := 0; i < ncolors; i++ {
+			c := tcell.ColorBlack + tcell.Color(i)
 			for j := 0; j < pillWidth; j++ {
 				if i == idx {
 					screen.SetContent(
-						((i%16)*pillWidth)+j,
-						1+(i/16),
+						((i%cols)*pillWidth)+j,
+						1+(i/cols),
 						'=', nil,
-						tcell.StyleDefault.Foreground(
-							tcell.ColorBlack+tcell.Color(i)))
+						tcell.StyleDefault.Foreground(c))
 				} else {
 					screen.SetContent(
-						((i%16)*pillWidth)+j,
-						1+(i/16),
+						((i%cols)*pillWidth)+j,
+						1+(i/cols),
 						' ', nil,
-						tcell.StyleDefault.Background(
-							tcell.ColorBlack+tcell.Color(i)))
+						tcell.StyleDefault.Background(c))
 				}
 			}
+			if ncolors <= 16 && i != idx && pillWidth > 2 {
+				PrintStringStyle(screen, (i%cols)*pillWidth+1, 1+(i/cols), ellipsize(paletteNames[i], pillWidth-1),
+					tcell.StyleDefault.Background(c).Foreground(contrastColor(c)))
+			}
 		}
-		screen.ShowCursor((idx%16)*pillWidth, 1+(idx/16))
+		describePaletteColor(screen, 2+rows, idx, ncolors)
+		screen.ShowCursor((idx%cols)*pillWidth, 1+(idx/cols))
 		screen.Show()
 
 		ev := screen.PollEvent()
@@ -452,18 +514,22 @@ func PickColor(screen tcell.Screen, prompt string) tcell.Color {
 		case *tcell.EventKey:
 			key := ParseTcellEvent(ev)
 			switch key {
+			case "C-g", "C-c":
+				if cancellable {
+					return def
+				}
 			case "M-<":
 				idx = 0
 			case "M->":
-				idx = 255
+				idx = last
 			case "UP", "C-p":
-				idx -= 16
+				idx -= cols
 			case "DOWN", "C-n":
-				idx += 16
+				idx += cols
 			case "M-UP", "M-p":
-				idx -= 64
+				idx -= 4 * cols
 			case "M-DOWN", "M-n":
-				idx += 64
+				idx += 4 * cols
 			case "M-LEFT", "M-b":
 				idx -= 4
 			case "M-RIGHT", "M-f":
@@ -473,20 +539,35 @@ func PickColor(screen tcell.Screen, prompt string) tcell.Color {
 			case "RIGHT", "C-f":
 				idx++
 			case "HOME", "C-a":
-				for idx%16 != 0 {
+				for idx%cols != 0 {
 					idx--
 				}
 			case "END", "C-e":
-				for idx%16 != 15 {
+				for idx%cols != cols-1 {
 					idx++
 				}
 			case "RET":
 				return tcell.ColorBlack + tcell.Color(idx)
+			case "TAB":
+				if c, ok := pickNamedColor(screen, prompt); ok {
+					if screen.Colors() >= 1<<24 {
+						return c
+					}
+					idx = paletteIndex(c, ncolors)
+				}
+			case "#":
+				text := Prompt(screen, "Colour (#rrggbb or name)", nil)
+				if c, ok := parseColor(text); ok {
+					if screen.Colors() >= 1<<24 {
+						return c
+					}
+					idx = paletteIndex(c, ncolors)
+				}
 			}
 			if idx < 0 {
 				idx = 0
-			} else if idx > 255 {
-				idx = 255
+			} else if idx > last {
+				idx = last
 			}
 		}
 	}
@@ -85,118 +85,140 @@ func PrintStringStyle(screen tcell.Screen, x, y int, s string, style tcell.Style
 	}
 }
 
-func pauseForAnyKey(screen tcell.Screen, currentRow int) {
-	PrintString(screen, 0, currentRow, "<More>")
-	screen.Show()
-	ev := screen.PollEvent()
-Loop:
-	for {
-		switch ev.(type) {
-		case *tcell.EventKey:
-			break Loop
-		default:
-			ev = screen.PollEvent()
+// Print string with a style, skipping any cells that fall outside of the
+// columns [left, right). Returns the column after the last rune.
+func printStringClip(screen tcell.Screen, x, y, left, right int, s string, style tcell.Style) int {
+	for _, ru := range s {
+		w := Runewidth(ru)
+		if x >= right {
+			break
 		}
+		if x >= left && x+w <= right {
+			PrintRuneStyle(screen, x, y, ru, style)
+		}
+		x += w
 	}
-	screen.Clear()
-	screen.Show()
+	return x
 }
 
-type lessRow struct {
-	data string
-	len  int
+// Returns s cut down to fit in width cells, ending with an ellipsis if any of
+// it had to be cut off.
+func ellipsize(s string, width int) string {
+	if RunewidthStr(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	w := 0
+	for i, ru := range s {
+		rw := Runewidth(ru)
+		if w+rw > width-1 {
+			return s[:i] + "…"
+		}
+		w += rw
+	}
+	return s
 }
 
-func lessDrawRows(screen tcell.Screen, sx, sy, cx, cy int, rows []lessRow, numrows int) {
-	for i := 0; i < sy-1; i++ {
-		ri := cy + i
-		if ri >= 0 && ri < numrows {
-			if cx < len(rows[ri].data) {
-				ts, _ := trimString(rows[ri].data, cx)
-				PrintString(screen, 0, i, ts)
-			}
-		}
+// StyledRun is a piece of text drawn in a single style.
+type StyledRun struct {
+	Text  string
+	Style tcell.Style
+}
+
+// StyledLine is a line of text made up of runs in different styles.
+type StyledLine []StyledRun
+
+// Splits plain text into StyledLines in the default style.
+func TextLines(s string) []StyledLine {
+	lines := strings.Split(s, "\n")
+	ret := make([]StyledLine, len(lines))
+	for i, line := range lines {
+		ret[i] = StyledLine{{strings.Replace(line, "\t", "        ", -1), tcell.StyleDefault}}
 	}
-	for i := 0; i < sx; i++ {
-		PrintRuneStyle(screen, i, sy-1, ' ', tcell.StyleDefault.Reverse(true))
+	return ret
+}
+
+// Returns how many cells wide the given line is.
+func (line StyledLine) Width() int {
+	ret := 0
+	for _, run := range line {
+		ret += RunewidthStr(run.Text)
 	}
-	PrintStringStyle(screen, 0, sy-1, "^C, ^G, q to quit. Arrow keys/Vi keys/Emacs keys to move.", tcell.StyleDefault.Reverse(true))
-	screen.Show()
+	return ret
 }
 
-//Prints all strings given to the screen, and allows the user to scroll through,
-//rather like less(1).
-func DisplayScreenMessage(screen tcell.Screen, messages ...string) {
-	screen.HideCursor()
-	rows := make([]lessRow, 0)
-	for _, msg := range messages {
-		for _, s := range strings.Split(msg, "\n") {
-			renderstring := strings.Replace(s, "\t", "        ", -1)
-			rows = append(rows, lessRow{renderstring, len(renderstring)})
-		}
+// Prints the styled line given, skipping any cells that fall outside of the
+// columns [left, right). Returns the column after the last rune.
+func printStyledLineClip(screen tcell.Screen, x, y, left, right int, line StyledLine) int {
+	for _, run := range line {
+		x = printStringClip(screen, x, y, left, right, run.Text, run.Style)
 	}
-	numrows := len(rows)
-	cy := 0
-	cx := 0
-	done := false
-	for !done {
-		screen.Clear()
-		sx, sy := screen.Size()
-		if sy > numrows {
-			cy = 0
-		}
-		lessDrawRows(screen, sx, sy, cx, cy, rows, numrows)
+	return x
+}
 
-		ev := screen.PollEvent()
-		switch ev := ev.(type) {
-		case *tcell.EventKey:
-			switch ParseTcellEvent(ev) {
-			case "q", "C-c", "C-g":
-				done = true
-			case "DOWN", "j", "C-n":
-				if cy < numrows+1-sy {
-					cy++
-				}
-			case "UP", "k", "C-p":
-				if cy > 0 {
-					cy--
-				}
-			case "Home", "C-a":
-				cx = 0
-			case "LEFT", "h", "C-b":
-				if cx > 0 {
-					cx--
-				}
-			case "RIGHT", "l", "C-f":
-				cx++
-			case "next", "C-v":
-				cy += sy - 2
-				if cy > numrows+1-sy {
-					cy = numrows + 1 - sy
-				}
-			case "prior", "M-v":
-				cy -= sy - 2
-				if cy < 0 {
-					cy = 0
-				}
-			case "g", "M-<":
-				cy = 0
-			case "G", "M->":
-				cy = numrows + 1 - sy
-			case "/", "C-s":
-				search := Prompt(screen, "Search", func(screen tcell.Screen, ssx, ssy int) {
-					lessDrawRows(screen, ssx, ssy, cx, cy, rows, numrows)
-				})
-				screen.HideCursor()
-				for offset, row := range rows[cy:] {
-					if strings.Contains(row.data, search) {
-						cy += offset
+// Word-wraps s to lines no more than width cells wide. Newlines in s always
+// start a new line, and words too long for a line are broken.
+func wrapText(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	ret := make([]string, 0)
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		linew := 0
+		for _, word := range strings.Fields(para) {
+			ww := RunewidthStr(word)
+			if linew > 0 && linew+1+ww > width {
+				ret = append(ret, line)
+				line, linew = "", 0
+			}
+			for ww > width {
+				// Break words that won't fit on a line of their own.
+				cut, cutw := 0, 0
+				for i, ru := range word {
+					if cutw+Runewidth(ru) > width-linew {
+						cut = i
 						break
 					}
+					cutw += Runewidth(ru)
+				}
+				if cut == 0 {
+					_, cut = utf8.DecodeRuneInString(word)
 				}
+				ret = append(ret, line+word[:cut])
+				line, linew = "", 0
+				word = word[cut:]
+				ww = RunewidthStr(word)
 			}
+			if linew > 0 {
+				line += " "
+				linew++
+			}
+			line += word
+			linew += ww
 		}
+		ret = append(ret, line)
 	}
+	return ret
+}
+
+func pauseForAnyKey(screen tcell.Screen, currentRow int) {
+	PrintString(screen, 0, currentRow, "<More>")
+	screen.Show()
+	ev := screen.PollEvent()
+Loop:
+	for {
+		switch ev.(type) {
+		case *tcell.EventKey:
+			break Loop
+		default:
+			ev = screen.PollEvent()
+		}
+	}
+	screen.Clear()
+	screen.Show()
 }
 
 func trimString(s string, coloff int) (string, int) {
@@ -0,0 +1,30 @@
+package termutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"exactly ten", 11, []string{"exactly ten"}},
+		{"  extra   spaces  ", 20, []string{"extra spaces"}},
+		{"one\ntwo\n\nthree", 20, []string{"one", "two", "", "three"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"hi abcdefghij", 4, []string{"hi", "abcd", "efgh", "ij"}},
+		{"日本語の文", 4, []string{"日本", "語の", "文"}},
+		{"abc", 0, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.in, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,703 @@
+package termutil
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type lessRow struct {
+	// The text with escape sequences taken out, for searching.
+	data string
+	line StyledLine
+	// The style at the end of the row, which the next row starts with.
+	end tcell.Style
+}
+
+// Makes a row from a line of text, starting in the given style.
+func newLessRow(s string, style tcell.Style) lessRow {
+	renderstring := strings.Replace(s, "\t", "        ", -1)
+	line, data, end := parseANSI(renderstring, style)
+	return lessRow{data, line, end}
+}
+
+// Returns the style the next row should start in.
+func (p *pager) endStyle() tcell.Style {
+	if len(p.rows) == 0 {
+		return tcell.StyleDefault
+	}
+	return p.rows[len(p.rows)-1].end
+}
+
+// pager holds the state of DisplayScreenMessage.
+type pager struct {
+	rows []lessRow
+	// cx is the number of runes scrolled off the left; cy is the top row.
+	cx, cy int
+	// The last search, and whether it went backwards.
+	search   *regexp.Regexp
+	backward bool
+	// The row the last search found, which may be below cy near the end.
+	found int
+	// A message shown in the status bar until the next key.
+	status string
+	// Where the rows come from, for DisplayScreenReader, and whether the
+	// view is following the end of it.
+	stream *pagerStream
+	follow bool
+	// Whether long rows are wrapped rather than cut off, and the width of
+	// the screen they're wrapped to.
+	wrap  bool
+	width int
+	// The name shown in the status bar, and whether there's a gutter with
+	// line numbers in it.
+	title   string
+	numbers bool
+	// A key (-, m, ' or M-g) waiting for the key after it, and the digits
+	// typed before a command like % or g.
+	prefix string
+	count  string
+	marks  Marks
+	// Set while the stream is still being read.
+	loading bool
+}
+
+// Marks maps the letters of the marks set in the pager to the lines they're
+// on, counting from 0. The mark '"' is where the pager was left.
+type Marks map[rune]int
+
+// The text of the pager's help screen.
+const pagerHelp = `Moving
+  j DOWN C-n         Down a line
+  k UP C-p           Up a line
+  C-v SPC PgDn       Down a screen
+  M-v PgUp           Up a screen
+  g M-<              Go to the start
+  G M->              Go to the end
+  LEFT C-b           Scroll left
+  RIGHT l C-f        Scroll right
+  C-a Home           Scroll all the way left
+  F                  Follow the end of the text as it arrives
+  <n>g :<n> M-g g    Go to line n
+  <n>%               Go to n percent of the way through
+
+Marks
+  m<letter>          Mark the top line
+  '<letter>          Go to a mark
+
+Searching
+  / C-s              Search forward for a regular expression
+  ? C-r              Search backward
+  n                  Go to the next match
+  N                  Go to the previous match
+
+Options
+  -N                 Show or hide line numbers
+  -S S               Wrap long lines, or cut them off
+
+  h H                Show this help
+  q C-g C-c          Quit`
+
+// pagerStream collects lines read in the background for DisplayScreenReader.
+// The last line is the one still being read, and may be empty.
+type pagerStream struct {
+	screen  tcell.Screen
+	mu      sync.Mutex
+	lines   []string
+	err     error
+	done    bool
+	closed  bool
+	pending bool
+}
+
+// wake asks the pager to redraw, unless it's already been asked and hasn't got
+// round to it yet. Must be called with s.mu held.
+func (s *pagerStream) wake() {
+	if !s.pending && !s.closed {
+		s.pending = s.screen.PostEvent(tcell.NewEventInterrupt(s)) == nil
+	}
+}
+
+func (s *pagerStream) read(r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		s.mu.Lock()
+		if n > 0 && !s.closed {
+			parts := strings.Split(string(buf[:n]), "\n")
+			s.lines[len(s.lines)-1] += parts[0]
+			s.lines = append(s.lines, parts[1:]...)
+			s.wake()
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+			}
+			s.done = true
+			s.wake()
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Brings the pager's rows up to date with the lines read so far. The pager's
+// last row is redone, as more of it may have arrived.
+func (s *pagerStream) pull(p *pager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = false
+	from := len(p.rows) - 1
+	if from < 0 {
+		from = 0
+	}
+	p.rows = p.rows[:from]
+	for _, line := range s.lines[from:] {
+		p.rows = append(p.rows, newLessRow(line, p.endStyle()))
+	}
+	p.loading = !s.done
+	if s.err != nil {
+		p.status = "Error reading input: " + s.err.Error()
+		s.err = nil
+	}
+}
+
+// Compiles a search pattern. As in less, the search ignores case unless the
+// pattern has an upper-case letter in it.
+func compileSearch(pattern string) (*regexp.Regexp, error) {
+	for _, ru := range pattern {
+		if unicode.IsUpper(ru) {
+			return regexp.Compile(pattern)
+		}
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Returns the largest useful value of cy for a screen sy rows high.
+func (p *pager) maxcy(sy int) int {
+	if !p.wrap {
+		if m := len(p.rows) + 1 - sy; m > 0 {
+			return m
+		}
+		return 0
+	}
+	h := 0
+	for ri := len(p.rows) - 1; ri >= 0; ri-- {
+		h += p.rowHeight(p.rows[ri])
+		if h > sy-1 {
+			if ri+1 < len(p.rows) {
+				return ri + 1
+			}
+			return ri
+		}
+	}
+	return 0
+}
+
+// Returns the number of columns rows are wrapped at; the last column is kept
+// for the continuation marker.
+func (p *pager) wrapWidth() int {
+	if w := p.width - p.gutter() - 1; w > 1 {
+		return w
+	}
+	return 1
+}
+
+// Returns the width of the line number gutter, or 0 if it's hidden.
+func (p *pager) gutter() int {
+	if !p.numbers {
+		return 0
+	}
+	return len(strconv.Itoa(len(p.rows))) + 1
+}
+
+// Returns how many screen lines a row takes up.
+func (p *pager) rowHeight(row lessRow) int {
+	if !p.wrap {
+		return 1
+	}
+	width := p.wrapWidth()
+	h, x := 1, 0
+	for _, run := range row.line {
+		for _, ru := range run.Text {
+			w := Runewidth(ru)
+			if x+w > width && x > 0 {
+				h++
+				x = 0
+			}
+			x += w
+		}
+	}
+	return h
+}
+
+// Returns how many rows fit on the screen from cy.
+func (p *pager) shown(sy int) int {
+	if !p.wrap {
+		return sy - 1
+	}
+	n, h := 0, 0
+	for ri := p.cy; ri < len(p.rows); ri++ {
+		h += p.rowHeight(p.rows[ri])
+		if h > sy-1 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// Returns how many rows a page moves, keeping one row of the old page on the
+// screen. Going back, it's the number of rows that fit above the top row and
+// it together.
+func (p *pager) pageStep(sy int, back bool) int {
+	if !p.wrap {
+		return sy - 2
+	}
+	n := p.shown(sy)
+	if back {
+		n = 0
+		h := 0
+		for ri := p.cy; ri >= 0 && ri < len(p.rows); ri-- {
+			h += p.rowHeight(p.rows[ri])
+			if h > sy-1 {
+				break
+			}
+			n++
+		}
+	}
+	if n-1 < 1 {
+		return 1
+	}
+	return n - 1
+}
+
+// Draws a row from screen line y, going no further than line maxy. Returns the
+// number of lines used.
+func (p *pager) drawRow(screen tcell.Screen, y, maxy, sx int, ri int) int {
+	row := p.rows[ri]
+	g := p.gutter()
+	if g > 0 {
+		num := strconv.Itoa(ri + 1)
+		PrintStringStyle(screen, g-1-len(num), y, num, themeStyle("pager.gutter"))
+	}
+	var matches [][]int
+	if p.search != nil {
+		matches = p.search.FindAllStringIndex(row.data, -1)
+	}
+	// i is the byte offset into row.data, which is all the runs put together.
+	x, skip, i, y0 := 0, p.cx, 0, y
+	if p.wrap {
+		skip = 0
+	}
+	for _, run := range row.line {
+		for j, ru := range run.Text {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			w := Runewidth(ru)
+			if p.wrap && x+w > p.wrapWidth() && x > 0 {
+				if y+1 >= maxy {
+					return y + 1 - y0
+				}
+				screen.SetContent(sx-1, y, '\\', nil, themeStyle("pager.wrap"))
+				y++
+				x = 0
+			} else if g+x+w > sx {
+				return y + 1 - y0
+			}
+			style := run.Style
+			for len(matches) > 0 && matches[0][1] <= i+j {
+				matches = matches[1:]
+			}
+			if len(matches) > 0 && matches[0][0] <= i+j {
+				style = themeStyle("pager.match")
+			}
+			PrintRuneStyle(screen, g+x, y, ru, style)
+			x += w
+		}
+		i += len(run.Text)
+	}
+	return y + 1 - y0
+}
+
+func (p *pager) draw(screen tcell.Screen, sx, sy int) {
+	p.width = sx
+	for y, ri := 0, p.cy; y < sy-1 && ri < len(p.rows); ri++ {
+		y += p.drawRow(screen, y, sy-1, sx, ri)
+	}
+	style := themeStyle("pager.status")
+	for i := 0; i < sx; i++ {
+		PrintRuneStyle(screen, i, sy-1, ' ', style)
+	}
+	pos := p.position(sy)
+	right := sx - RunewidthStr(pos) - 1
+	printStringClip(screen, right, sy-1, 0, sx, pos, style)
+	status := p.status
+	switch {
+	case status != "":
+	case p.follow:
+		status = "Following the end of the input; any key to stop."
+	case p.title != "":
+		status = p.title
+	default:
+		status = "h for help, q to quit."
+	}
+	printStringClip(screen, 0, sy-1, 0, right-1, ellipsize(status, right-1), style)
+	screen.Show()
+}
+
+// Returns where the view is, e.g. "lines 21-40/100 40%". The percentage is of
+// the way through to the last line on the screen. While the text is still
+// arriving, the total has a + after it.
+func (p *pager) position(sy int) string {
+	n := len(p.rows)
+	if n == 0 {
+		return "(empty)"
+	}
+	last := p.cy + p.shown(sy)
+	if last > n {
+		last = n
+	}
+	if last <= p.cy {
+		last = p.cy + 1
+	}
+	more := ""
+	if p.loading {
+		more = "+"
+	}
+	return fmt.Sprintf("lines %d-%d/%d%s %d%%", p.cy+1, last, n, more, last*100/n)
+}
+
+// Moves the top of the screen to row ri, as near as it can.
+func (p *pager) goTo(ri, sy int) {
+	if ri > p.maxcy(sy) {
+		ri = p.maxcy(sy)
+	}
+	if ri < 0 {
+		ri = 0
+	}
+	p.cy = ri
+}
+
+// Asks for a line number and goes to it.
+func (p *pager) promptLine(screen tcell.Screen, sy int) {
+	text := Prompt(screen, "Go to line", func(screen tcell.Screen, ssx, ssy int) {
+		p.draw(screen, ssx, ssy)
+	})
+	screen.HideCursor()
+	if text == "" {
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		p.status = "Not a line number: " + text
+		return
+	}
+	p.goTo(n-1, sy)
+}
+
+// Handles the key after a prefix key.
+func (p *pager) prefixed(screen tcell.Screen, prefix, key string, sy int) {
+	if key == "C-g" {
+		return
+	}
+	switch prefix {
+	case "-":
+		switch key {
+		case "N":
+			p.numbers = !p.numbers
+		case "S":
+			p.toggleWrap()
+		default:
+			p.status = "No such option"
+		}
+	case "m", "'":
+		ru, size := utf8.DecodeRuneInString(key)
+		if size != len(key) || !unicode.IsLetter(ru) {
+			p.status = "Marks must be letters"
+		} else if prefix == "m" {
+			p.marks[ru] = p.cy
+			p.status = "Mark set"
+		} else if ri, ok := p.marks[ru]; ok {
+			p.goTo(ri, sy)
+		} else {
+			p.status = "Mark not set"
+		}
+	case "M-g":
+		if key == "g" || key == "M-g" {
+			p.promptLine(screen, sy)
+		}
+	}
+}
+
+// Turns wrapping long lines on or off.
+func (p *pager) toggleWrap() {
+	p.wrap = !p.wrap
+	p.cx = 0
+	if p.wrap {
+		p.status = "Wrapping long lines"
+	} else {
+		p.status = "Cutting off long lines"
+	}
+}
+
+// Moves to the next row matching the last search, starting at row from and
+// going backwards if back is set, wrapping around at the ends.
+func (p *pager) find(from int, back bool, sy int) {
+	if p.search == nil {
+		p.status = "No previous search"
+		return
+	}
+	n := len(p.rows)
+	if n == 0 {
+		p.status = "Pattern not found"
+		return
+	}
+	step := 1
+	if back {
+		step = -1
+	}
+	for i := 0; i < n; i++ {
+		ri := from + i*step
+		wrapped := ri < 0 || ri >= n
+		ri = ((ri % n) + n) % n
+		if p.search.MatchString(p.rows[ri].data) {
+			if wrapped {
+				p.status = "Wrapped"
+			}
+			p.cy, p.found = ri, ri
+			if p.cy > p.maxcy(sy) {
+				p.cy = p.maxcy(sy)
+			}
+			return
+		}
+	}
+	p.status = "Pattern not found"
+}
+
+// Moves to the next match after (or before) the last one found, if it's still
+// on the screen, or else the top row.
+func (p *pager) findNext(back bool, sy int) {
+	from := p.cy
+	if p.found >= p.cy && p.found < p.cy+p.shown(sy) {
+		from = p.found
+	}
+	if back {
+		p.find(from-1, back, sy)
+	} else {
+		p.find(from+1, back, sy)
+	}
+}
+
+func (p *pager) run(screen tcell.Screen) {
+	for {
+		screen.Clear()
+		sx, sy := screen.Size()
+		p.width = sx
+		if p.stream != nil {
+			p.stream.pull(p)
+		}
+		if p.follow || p.cy > p.maxcy(sy) {
+			p.cy = p.maxcy(sy)
+		}
+		p.draw(screen, sx, sy)
+
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			p.status = ""
+			if p.follow {
+				p.follow = false
+				break
+			}
+			key := ParseTcellEvent(ev)
+			if p.prefix != "" {
+				prefix := p.prefix
+				p.prefix = ""
+				p.prefixed(screen, prefix, key, sy)
+				break
+			}
+			if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+				p.count += key
+				p.status = p.count
+				break
+			}
+			count := -1
+			if p.count != "" {
+				count, _ = strconv.Atoi(p.count)
+				p.count = ""
+			}
+			switch key {
+			case "q", "C-c", "C-g":
+				return
+			case "DOWN", "j", "C-n":
+				if p.cy < p.maxcy(sy) {
+					p.cy++
+				}
+			case "UP", "k", "C-p":
+				if p.cy > 0 {
+					p.cy--
+				}
+			case "Home", "C-a":
+				p.cx = 0
+			case "LEFT", "C-b":
+				if p.cx > 0 {
+					p.cx--
+				}
+			case "RIGHT", "l", "C-f":
+				if !p.wrap {
+					p.cx++
+				}
+			case "next", "C-v", " ":
+				p.cy += p.pageStep(sy, false)
+				if p.cy > p.maxcy(sy) {
+					p.cy = p.maxcy(sy)
+				}
+			case "prior", "M-v":
+				p.cy -= p.pageStep(sy, true)
+				if p.cy < 0 {
+					p.cy = 0
+				}
+			case "g", "M-<":
+				// With a count, g and G go to that line, as in less.
+				if count > 0 {
+					p.goTo(count-1, sy)
+				} else {
+					p.cy = 0
+				}
+			case "G", "M->":
+				if count > 0 {
+					p.goTo(count-1, sy)
+				} else {
+					p.cy = p.maxcy(sy)
+				}
+			case "%":
+				if count >= 0 {
+					p.goTo(count*len(p.rows)/100, sy)
+				}
+			case ":":
+				p.promptLine(screen, sy)
+			case "m", "'", "M-g":
+				p.prefix = key
+				p.status = key + "-"
+			case "F":
+				p.follow = true
+			case "S":
+				p.toggleWrap()
+			case "-":
+				p.prefix = key
+				p.status = "Toggle option: -"
+			case "h", "H":
+				DisplayScreenMessageTitle(screen, "Help", pagerHelp)
+			case "/", "C-s", "?", "C-r":
+				back := key == "?" || key == "C-r"
+				prompt := "Search"
+				if back {
+					prompt = "Search backward"
+				}
+				cancelled := false
+				pattern := EditDynamicWithCallback(screen, "", prompt, func(screen tcell.Screen, ssx, ssy int) {
+					p.draw(screen, ssx, ssy)
+				}, func(buffer, key string) string {
+					cancelled = key == "C-g" || key == "C-c"
+					return buffer
+				})
+				screen.HideCursor()
+				if cancelled {
+					break
+				}
+				if pattern == "" {
+					// As in less, an empty pattern repeats the last search.
+					p.backward = back
+					p.findNext(back, sy)
+					break
+				}
+				re, err := compileSearch(pattern)
+				if err != nil {
+					p.status = "Bad pattern: " + err.Error()
+					break
+				}
+				p.search, p.backward = re, back
+				p.find(p.cy, back, sy)
+			case "n":
+				p.findNext(p.backward, sy)
+			case "N":
+				p.findNext(!p.backward, sy)
+			}
+		}
+	}
+}
+
+// Prints all strings given to the screen, and allows the user to scroll through,
+// rather like less(1). Searching with / (or ? to search backwards) takes a
+// regular expression, which ignores case unless it has upper-case letters in
+// it; matches are highlighted, and n and N go to the next and previous match.
+// Colours and attributes set with ANSI escape sequences are shown, as with
+// less -R, so the output of git, ls --color and the like looks as it would in
+// the terminal; each string starts in the default style. S switches between
+// cutting off long lines, which can then be scrolled sideways, and wrapping
+// them, like less's -S, and -N shows line numbers. The status bar shows which
+// lines are on the screen, and h shows the rest of the keys.
+func DisplayScreenMessage(screen tcell.Screen, messages ...string) {
+	DisplayScreenMessageTitle(screen, "", messages...)
+}
+
+// As DisplayScreenMessage, but shows a title (e.g. the name of the file) in the
+// status bar.
+func DisplayScreenMessageTitle(screen tcell.Screen, title string, messages ...string) {
+	DisplayScreenMessageMarks(screen, title, nil, messages...)
+}
+
+// As DisplayScreenMessageTitle, but with marks: m and a letter marks the top
+// line, and ' and the letter goes back to it. Starts with the marks given,
+// at the line marked '"' if there is one, and returns the marks when the user
+// quits, with '"' set to where they were; passing these back in the next time
+// the same text is shown picks up where the user left off.
+func DisplayScreenMessageMarks(screen tcell.Screen, title string, marks Marks, messages ...string) Marks {
+	screen.HideCursor()
+	p := &pager{found: -1, title: title, marks: Marks{}}
+	for ru, ri := range marks {
+		p.marks[ru] = ri
+	}
+	if ri, ok := p.marks['"']; ok && ri > 0 {
+		p.cy = ri
+	}
+	for _, msg := range messages {
+		style := tcell.StyleDefault
+		for _, s := range strings.Split(msg, "\n") {
+			row := newLessRow(s, style)
+			p.rows = append(p.rows, row)
+			style = row.end
+		}
+	}
+	p.run(screen)
+	p.marks['"'] = p.cy
+	return p.marks
+}
+
+// As DisplayScreenMessageTitle, but reads the text from r, showing it as it
+// arrives; this is handy for the output of a command. F follows the end of the
+// text as more comes in, like tail -f, until the next key. r is closed when the
+// pager is closed, which stops the reading; a writer on the other end of a
+// pipe gets an error rather than blocking.
+func DisplayScreenReader(screen tcell.Screen, title string, r io.ReadCloser) {
+	screen.HideCursor()
+	stream := &pagerStream{screen: screen, lines: []string{""}}
+	go stream.read(r)
+	p := &pager{found: -1, title: title, stream: stream, marks: Marks{}}
+	p.run(screen)
+	stream.mu.Lock()
+	stream.closed = true
+	stream.mu.Unlock()
+	r.Close()
+}
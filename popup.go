@@ -0,0 +1,188 @@
+package termutil
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+type savedCell struct {
+	mainc rune
+	combc []rune
+	style tcell.Style
+}
+
+// screenRegion remembers what was on a part of the screen, so that it can be
+// put back when a pop-up drawn over it goes away.
+type screenRegion struct {
+	x, y, w, h int
+	cells      []savedCell
+}
+
+func saveRegion(screen tcell.Screen, x, y, w, h int) *screenRegion {
+	r := &screenRegion{x, y, w, h, make([]savedCell, 0, w*h)}
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			mainc, combc, style, _ := screen.GetContent(i, j)
+			r.cells = append(r.cells, savedCell{mainc, combc, style})
+		}
+	}
+	return r
+}
+
+func (r *screenRegion) restore(screen tcell.Screen) {
+	for j := 0; j < r.h; j++ {
+		for i := 0; i < r.w; i++ {
+			c := r.cells[j*r.w+i]
+			screen.SetContent(r.x+i, r.y+j, c.mainc, c.combc, c.style)
+		}
+	}
+}
+
+// Draws a box with a border, and its title set into the top edge. The inside
+// of the box is cleared.
+func drawBox(screen tcell.Screen, x, y, w, h int, title string, style tcell.Style) {
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			ru := ' '
+			switch {
+			case j == y && i == x:
+				ru = '┌'
+			case j == y && i == x+w-1:
+				ru = '┐'
+			case j == y+h-1 && i == x:
+				ru = '└'
+			case j == y+h-1 && i == x+w-1:
+				ru = '┘'
+			case j == y || j == y+h-1:
+				ru = '─'
+			case i == x || i == x+w-1:
+				ru = '│'
+			}
+			screen.SetContent(i, j, ru, nil, style)
+		}
+	}
+	if title != "" {
+		printStringClip(screen, x+2, y, x+1, x+w-1, ellipsize(" "+title+" ", w-3), themeOver(style, "border.title"))
+	}
+}
+
+// popupRect fits a box of the wanted size on the screen. If px is negative the
+// box is centred; otherwise it is placed just below the cell (px, py), or just
+// above it if there isn't room below.
+func popupRect(sx, sy, w, h, px, py int) (int, int, int, int) {
+	if w > sx {
+		w = sx
+	}
+	if h > sy {
+		h = sy
+	}
+	var x, y int
+	if px < 0 {
+		x = (sx - w) / 2
+		y = (sy - h) / 2
+	} else {
+		x = px
+		y = py + 1
+		if y+h > sy {
+			y = py - h
+		}
+		if y < 0 {
+			y = sy - h
+		}
+		if x+w > sx {
+			x = sx - w
+		}
+	}
+	return x, y, w, h
+}
+
+// As ChoiceIndex, but the choices are drawn in a box in the middle of the
+// screen, over whatever is already there. The screen is put back as it was
+// when the box closes.
+func ChoiceIndexPopup(screen tcell.Screen, title string, choices []string, def int) int {
+	return ChoiceIndexPopupAt(screen, -1, -1, title, choices, def)
+}
+
+// As ChoiceIndexPopup, but the box is drawn just below (or above) the cell x, y
+// - usually where the cursor is. If x is negative, the box is centred.
+func ChoiceIndexPopupAt(screen tcell.Screen, x, y int, title string, choices []string, def int) int {
+	return choiceSourcePopupAt(screen, x, y, title, StringSource(choices), def)
+}
+
+// As ChoiceIndexPopup, but takes a slice of ChoiceItems.
+func ChoiceItemIndexPopup(screen tcell.Screen, title string, items []ChoiceItem, def int) int {
+	return ChoiceItemIndexPopupAt(screen, -1, -1, title, items, def)
+}
+
+// As ChoiceIndexPopupAt, but takes a slice of ChoiceItems.
+func ChoiceItemIndexPopupAt(screen tcell.Screen, x, y int, title string, items []ChoiceItem, def int) int {
+	return choiceSourcePopupAt(screen, x, y, title, ItemSource(items), def)
+}
+
+func choiceSourcePopupAt(screen tcell.Screen, x, y int, title string, source ChoiceSource, def int) int {
+	menu := choiceMenu{
+		title:  title,
+		source: source,
+		def:    def,
+		popup:  true,
+		px:     x,
+		py:     y,
+	}
+	return menu.run(screen)
+}
+
+// As PressKey, but the prompt is shown in a box in the middle of the screen,
+// over whatever is already there. The screen is put back as it was afterwards.
+func PressKeyPopup(screen tcell.Screen, p string, keys ...string) string {
+	pm := pressKeyPrompt(p, keys)
+	seq := keySequence{keys: keys}
+	var saved *screenRegion
+	defer func() {
+		if saved != nil {
+			saved.restore(screen)
+			screen.HideCursor()
+			screen.Show()
+		}
+	}()
+	draw := func() {
+		if saved != nil {
+			saved.restore(screen)
+		}
+		msg := pm + seq.String()
+		sx, sy := screen.Size()
+		x, y, w, h := popupRect(sx, sy, RunewidthStr(msg)+5, 3, -1, -1)
+		saved = saveRegion(screen, x, y, w, h)
+		drawBox(screen, x, y, w, h, "", themeStyle("border"))
+		end := printStringClip(screen, x+2, y+1, x+1, x+w-1, msg, tcell.StyleDefault)
+		screen.ShowCursor(end+1, y+1)
+		screen.Show()
+	}
+	draw()
+	for {
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventResize, *tcell.EventInterrupt:
+			// Interrupts, such as from a MessageArea, just redraw.
+			draw()
+		case *tcell.EventKey:
+			if key, ok := seq.press(screen, ParseTcellEvent(ev)); ok {
+				return key
+			}
+			draw()
+		}
+	}
+}
+
+// As YesNo, but the prompt is shown in a pop-up box.
+func YesNoPopup(screen tcell.Screen, p string) bool {
+	ret, _ := yesNoChoice(func(keys ...string) string {
+		return PressKeyPopup(screen, p, keys...)
+	}, false)
+	return ret
+}
+
+// As YesNoCancel, but the prompt is shown in a pop-up box.
+func YesNoCancelPopup(screen tcell.Screen, p string) (bool, error) {
+	return yesNoChoice(func(keys ...string) string {
+		return PressKeyPopup(screen, p, keys...)
+	}, true)
+}
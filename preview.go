@@ -0,0 +1,85 @@
+package termutil
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// previewPane is the part of the screen next to (or below) a menu which shows a
+// preview of the selected entry.
+type previewPane struct {
+	x, y, w, h int
+	// selection is the entry that lines are a preview of.
+	selection int
+	lines     []StyledLine
+	scrolled  int
+}
+
+// layout splits the screen between the menu and the pane. The pane goes on the
+// right if there's room for 40 columns on each side; otherwise it goes below.
+// The menu's right edge, rows and page size are shrunk to fit.
+func (pv *previewPane) layout(sx, sy int, right, rows, page *int) {
+	if sx/2 >= 40 {
+		*right = sx / 2
+		pv.x, pv.y, pv.w, pv.h = sx/2+1, 1, sx-sx/2-1, sy-1
+	} else {
+		*rows = (sy - 1) / 2
+		*page = *rows - 1
+		pv.x, pv.y, pv.w, pv.h = 0, *rows+2, sx, sy-*rows-2
+	}
+}
+
+func (pv *previewPane) set(selection int, lines []StyledLine) {
+	pv.selection = selection
+	pv.lines = lines
+	pv.scrolled = 0
+}
+
+func (pv *previewPane) scroll(by int) {
+	pv.scrolled += by
+	if pv.scrolled > len(pv.lines)-pv.h {
+		pv.scrolled = len(pv.lines) - pv.h
+	}
+	if pv.scrolled < 0 {
+		pv.scrolled = 0
+	}
+}
+
+func (pv *previewPane) contains(x, y int) bool {
+	return x >= pv.x && x < pv.x+pv.w && y >= pv.y && y < pv.y+pv.h
+}
+
+func (pv *previewPane) draw(screen tcell.Screen) {
+	divider := themeStyle("preview.divider")
+	if pv.y == 1 {
+		for j := pv.y; j < pv.y+pv.h; j++ {
+			screen.SetContent(pv.x-1, j, '│', nil, divider)
+		}
+	} else {
+		for i := pv.x; i < pv.x+pv.w; i++ {
+			screen.SetContent(i, pv.y-1, '─', nil, divider)
+		}
+	}
+	for j := 0; j < pv.h && pv.scrolled+j < len(pv.lines); j++ {
+		printStyledLineClip(screen, pv.x+1, pv.y+j, pv.x+1, pv.x+pv.w, pv.lines[pv.scrolled+j])
+	}
+}
+
+// As ChoiceIndex, but the screen is split, and a preview of the selected choice
+// is shown on the right (or at the bottom, if the screen is narrow). The preview
+// function is called with the index of the selected choice, and returns the
+// lines to show; TextLines will turn plain text into lines. M-n and M-p (or
+// the mouse wheel, if UseMouse is set) scroll the preview.
+func ChoiceIndexPreview(screen tcell.Screen, title string, choices []string, def int, preview func(int) []StyledLine) int {
+	return ChoiceSourceIndexPreview(screen, title, StringSource(choices), def, preview)
+}
+
+// As ChoiceIndexPreview, but the entries come from a ChoiceSource.
+func ChoiceSourceIndexPreview(screen tcell.Screen, title string, source ChoiceSource, def int, preview func(int) []StyledLine) int {
+	menu := choiceMenu{
+		title:   title,
+		source:  source,
+		def:     def,
+		preview: preview,
+	}
+	return menu.run(screen)
+}
@@ -0,0 +1,158 @@
+package termutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// streamSource is a ChoiceSource that grows as items arrive on a channel.
+type streamSource struct {
+	screen  tcell.Screen
+	mu      sync.Mutex
+	items   []ChoiceItem
+	open    bool
+	closed  bool
+	pending bool
+	frame   int
+	// stopped is closed when the menu returns.
+	stopped chan struct{}
+}
+
+func (s *streamSource) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func (s *streamSource) At(i int) ChoiceItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.items[i]
+}
+
+// wake asks the menu to redraw, unless it's already been asked and hasn't got
+// round to it yet; this stops a fast producer filling up the event queue.
+// Must be called with s.mu held.
+func (s *streamSource) wake() {
+	if !s.pending && !s.closed {
+		s.pending = s.screen.PostEvent(tcell.NewEventInterrupt(s)) == nil
+	}
+}
+
+// status returns the count and, while items are still arriving, a spinner.
+func (s *streamSource) status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = false
+	if s.open {
+		s.frame = (s.frame + 1) % len(spinnerFrames)
+		return fmt.Sprintf(" (%d) %s", len(s.items), spinnerFrames[s.frame])
+	}
+	return fmt.Sprintf(" (%d)", len(s.items))
+}
+
+func (s *streamSource) read(ctx context.Context, items <-chan ChoiceItem) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case item, ok := <-items:
+			s.mu.Lock()
+			if !ok {
+				s.open = false
+				s.wake()
+				s.mu.Unlock()
+				return
+			}
+			if !s.closed {
+				s.items = append(s.items, item)
+				s.wake()
+			}
+			s.mu.Unlock()
+		case <-ticker.C:
+			// Keep the spinner turning.
+			s.mu.Lock()
+			s.wake()
+			s.mu.Unlock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.open = false
+			s.wake()
+			s.mu.Unlock()
+			return
+		case <-s.stopped:
+			// Drain the channel, so the producer doesn't block, until it's
+			// closed or the producer is told to stop.
+			ticker.Stop()
+			for {
+				select {
+				case _, ok := <-items:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// Allows the user to select one of a list of choices which arrive on a
+// channel, for when they're slow to produce (e.g. search results). The user can
+// move around while items are still arriving; the title shows how many there
+// are, and a spinner until the channel is closed. Items keep their places as
+// more arrive, so the selection doesn't move. Returns the index and the item
+// selected, or -1 if the user cancelled. The producer should close the channel
+// when it's done, or give up once ctx is done; cancel ctx after the chooser
+// returns so that it can stop early, e.g.
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	go func() {
+//		defer close(items)
+//		for ... {
+//			select {
+//			case items <- item:
+//			case <-ctx.Done():
+//				return
+//			}
+//		}
+//	}()
+//	i, item := ChoiceStream(ctx, screen, "Results", items)
+//	cancel()
+//
+// Anything sent after the chooser returns is read and thrown away until the
+// channel is closed or ctx is done, so a producer that doesn't watch ctx won't
+// block. If ctx is done while the chooser is open, it stops waiting for more.
+func ChoiceStream(ctx context.Context, screen tcell.Screen, title string, items <-chan ChoiceItem) (int, ChoiceItem) {
+	return ChoiceStreamCallback(ctx, screen, title, items, nil)
+}
+
+// As ChoiceStream, but calls a function after drawing the interface, passing
+// it the current selected choice, screen width, and screen height.
+func ChoiceStreamCallback(ctx context.Context, screen tcell.Screen, title string, items <-chan ChoiceItem, f func(tcell.Screen, int, int, int)) (int, ChoiceItem) {
+	source := &streamSource{screen: screen, open: true, stopped: make(chan struct{})}
+	go source.read(ctx, items)
+	defer close(source.stopped)
+	menu := choiceMenu{
+		title:    title,
+		source:   source,
+		def:      -1,
+		callback: f,
+		status:   source.status,
+	}
+	idx := menu.run(screen)
+	source.mu.Lock()
+	source.closed = true
+	defer source.mu.Unlock()
+	if idx < 0 || idx >= len(source.items) {
+		return -1, ChoiceItem{}
+	}
+	return idx, source.items[idx]
+}
@@ -0,0 +1,104 @@
+package termutil
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Text used to preview a style.
+const stylePreviewText = "The quick brown fox jumps over the lazy dog"
+
+var styleAttrs = []struct {
+	name string
+	attr tcell.AttrMask
+}{
+	{"Bold", tcell.AttrBold},
+	{"Italic", tcell.AttrItalic},
+	{"Underline", tcell.AttrUnderline},
+	{"Reverse", tcell.AttrReverse},
+	{"Dim", tcell.AttrDim},
+	{"Blink", tcell.AttrBlink},
+}
+
+// Allows the user to edit a style: its foreground and background colours and
+// its attributes, with a preview of some text in the style. UP and DOWN (or C-p
+// and C-n) move between the settings, and RET or SPC changes the selected one -
+// colours are chosen with PickColorRGB, and d puts a colour back to the
+// terminal's default. Selecting Done returns the new style; C-g returns def.
+func PickStyle(screen tcell.Screen, prompt string, def tcell.Style) tcell.Style {
+	style := def
+	selection := 0
+	// Rows are foreground, background, the attributes, then Done.
+	done := 2 + len(styleAttrs)
+	for {
+		fg, bg, attrs := style.Decompose()
+		sx, sy := screen.Size()
+		screen.Clear()
+		screen.HideCursor()
+		PrintString(screen, 0, 0, prompt)
+		for i, c := range []tcell.Color{fg, bg} {
+			y := 2 + i
+			PrintString(screen, 3, y, []string{"Foreground", "Background"}[i])
+			if c == tcell.ColorDefault {
+				PrintStringStyle(screen, 15, y, "default", themeStyle("hint"))
+			} else {
+				for j := 0; j < 4; j++ {
+					screen.SetContent(15+j, y, ' ', nil, tcell.StyleDefault.Background(c))
+				}
+				PrintString(screen, 20, y, hexColor(c))
+			}
+		}
+		for i, a := range styleAttrs {
+			mark := "[ ] "
+			if attrs&a.attr != 0 {
+				mark = "[x] "
+			}
+			PrintString(screen, 3, 4+i, mark+a.name)
+		}
+		PrintStringStyle(screen, 3, 4+len(styleAttrs), "Done", themeStyle("menu.header"))
+		PrintString(screen, 1, 2+selection, ">")
+
+		py := 6 + len(styleAttrs)
+		if py < sy {
+			printStringClip(screen, 3, py, 3, sx, stylePreviewText, style)
+		}
+		screen.Show()
+
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			switch ParseTcellEvent(ev) {
+			case "C-c", "C-g":
+				return def
+			case "UP", "C-p":
+				if selection > 0 {
+					selection--
+				}
+			case "DOWN", "C-n":
+				if selection < done {
+					selection++
+				}
+			case "M-<":
+				selection = 0
+			case "M->":
+				selection = done
+			case "d":
+				if selection == 0 {
+					style = style.Foreground(tcell.ColorDefault)
+				} else if selection == 1 {
+					style = style.Background(tcell.ColorDefault)
+				}
+			case "RET", " ":
+				switch {
+				case selection == 0:
+					style = style.Foreground(PickColorRGB(screen, "Foreground for "+prompt, fg))
+				case selection == 1:
+					style = style.Background(PickColorRGB(screen, "Background for "+prompt, bg))
+				case selection == done:
+					return style
+				default:
+					style = style.Attributes(attrs ^ styleAttrs[selection-2].attr)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package termutil
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Gap between columns of a table, in cells.
+const tableGap = 2
+
+// Allows the user to select a row from a table. Takes a title, column headers,
+// rows of cells and a default selection. LEFT and RIGHT scroll by whole
+// columns; s sorts by the leftmost visible column, and pressing it again
+// reverses the order. Returns an index into rows (regardless of sorting), or def
+// if the user cancelled.
+func ChoiceTable(screen tcell.Screen, title string, headers []string, rows [][]string, def int) int {
+	return ChoiceTableCallback(screen, title, headers, rows, def, nil)
+}
+
+// As ChoiceTable, but calls a function after drawing the interface, passing it
+// the selected row (as an index into rows), screen width, and screen height.
+func ChoiceTableCallback(screen tcell.Screen, title string, headers []string, rows [][]string, def int, f func(tcell.Screen, int, int, int)) int {
+	ncols := len(headers)
+	for _, row := range rows {
+		if len(row) > ncols {
+			ncols = len(row)
+		}
+	}
+	widths := make([]int, ncols)
+	for i, h := range headers {
+		// Leave room for the sort indicator.
+		widths[i] = RunewidthStr(h) + 1
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := RunewidthStr(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	// order maps displayed rows to indices into rows.
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sortcol := -1
+	reverse := false
+
+	nr := len(rows) - 1
+	selection := 0
+	for i, ri := range order {
+		if ri == def {
+			selection = i
+		}
+	}
+	offset := 0
+	col := 0
+	for {
+		sx, sy := screen.Size()
+		screen.HideCursor()
+		screen.Clear()
+		PrintString(screen, 0, 0, title)
+		for selection < offset {
+			offset -= 5
+			if offset < 0 {
+				offset = 0
+			}
+		}
+		for selection-offset >= sy-2 {
+			offset += 5
+			if offset >= nr {
+				offset = nr
+			}
+		}
+
+		maxw := sx - 2
+		if ncols-col > 1 {
+			maxw = sx / 2
+		}
+		headstyle := themeStyle("table.header")
+		x := 2
+		for c := col; c < ncols && x < sx; c++ {
+			w := widths[c]
+			if w > maxw {
+				w = maxw
+			}
+			if x+w > sx {
+				w = sx - x
+			}
+			h := ""
+			if c < len(headers) {
+				h = headers[c]
+			}
+			if c == sortcol {
+				if reverse {
+					h += "▼"
+				} else {
+					h += "▲"
+				}
+			}
+			printStringClip(screen, x, 1, x, sx, ellipsize(h, w), headstyle)
+			for i := offset; i <= nr && i-offset < sy-2; i++ {
+				row := rows[order[i]]
+				if c < len(row) {
+					PrintString(screen, x, i+2-offset, ellipsize(row[c], w))
+				}
+			}
+			x += w + tableGap
+		}
+		if col > 0 {
+			PrintString(screen, 0, 1, "←")
+		}
+		if nr >= 0 {
+			PrintString(screen, 1, (selection+2)-offset, ">")
+		}
+		if f != nil && nr >= 0 {
+			f(screen, order[selection], sx, sy)
+		}
+		screen.Show()
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-v", "next":
+				selection += sy - 5
+				if selection > nr {
+					selection = nr
+				}
+			case "M-v", "prior":
+				selection -= sy - 5
+				if selection < 0 {
+					selection = 0
+				}
+			case "C-c", "C-g":
+				return def
+			case "UP", "C-p":
+				if selection > 0 {
+					selection--
+				}
+			case "DOWN", "C-n":
+				if selection < nr {
+					selection++
+				}
+			case "LEFT", "C-b":
+				if col > 0 {
+					col--
+				}
+			case "RIGHT", "C-f":
+				if col < ncols-1 {
+					col++
+				}
+			case "C-a", "Home":
+				col = 0
+			case "M-<":
+				selection = 0
+			case "M->":
+				selection = nr
+			case "s":
+				if nr < 0 {
+					break
+				}
+				if sortcol == col {
+					reverse = !reverse
+				} else {
+					sortcol = col
+					reverse = false
+				}
+				selected := order[selection]
+				sortTableOrder(order, rows, sortcol, reverse)
+				for i, ri := range order {
+					if ri == selected {
+						selection = i
+					}
+				}
+			case "RET":
+				if nr >= 0 {
+					return order[selection]
+				}
+			}
+		}
+	}
+}
+
+// sortTableOrder sorts order by column col of rows. Numbers are compared
+// numerically, so that columns of sizes or PIDs sort the way you'd expect, and
+// come before everything else, such as placeholders like "-" or "n/a".
+func sortTableOrder(order []int, rows [][]string, col int, reverse bool) {
+	cell := func(i int) string {
+		if col < len(rows[order[i]]) {
+			return rows[order[i]][col]
+		}
+		return ""
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := cell(i), cell(j)
+		if reverse {
+			a, b = b, a
+		}
+		af, an := parseNumber(a)
+		bf, bn := parseNumber(b)
+		switch {
+		case an && bn:
+			return af < bf
+		case an != bn:
+			return an
+		}
+		return a < b
+	})
+}
+
+// parseNumber returns the value of a cell, and whether it's a number. NaN
+// isn't counted, as it doesn't compare with anything.
+func parseNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil && !math.IsNaN(f)
+}
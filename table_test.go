@@ -0,0 +1,36 @@
+package termutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortTableOrder(t *testing.T) {
+	tests := []struct {
+		cells   []string
+		reverse bool
+		want    []string
+	}{
+		{[]string{"b", "a", "c"}, false, []string{"a", "b", "c"}},
+		{[]string{"10", "9", "1.5"}, false, []string{"1.5", "9", "10"}},
+		{[]string{"10", "a", "9", "-", "n/a", "2"}, false, []string{"2", "9", "10", "-", "a", "n/a"}},
+		{[]string{"10", "a", "9", "-", "n/a", "2"}, true, []string{"n/a", "a", "-", "10", "9", "2"}},
+		{[]string{"NaN", "3", "1"}, false, []string{"1", "3", "NaN"}},
+	}
+	for _, tt := range tests {
+		rows := make([][]string, len(tt.cells))
+		order := make([]int, len(tt.cells))
+		for i, c := range tt.cells {
+			rows[i] = []string{"x", c}
+			order[i] = i
+		}
+		sortTableOrder(order, rows, 1, tt.reverse)
+		got := make([]string, len(order))
+		for i, ri := range order {
+			got[i] = rows[ri][1]
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sorting %q (reverse %v) gave %q, want %q", tt.cells, tt.reverse, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package termutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Theme maps the names of the parts of the interface (e.g. "selection",
+// "pager.status") to the styles they're drawn in. Names missing from a theme
+// fall back on DefaultTheme.
+//
+// Themes can be read and written as text, one style per line:
+//
+//	# Comments start with a hash
+//	selection = bold white on #303030
+//	pager.status = reverse
+//	message.error = red
+//
+// A style is made of attributes (bold, italic, underline, reverse, dim, blink
+// and strikethrough), then an optional foreground colour, then "on" and an
+// optional background colour. Colours are names from tcell.ColorNames, hex
+// codes, palette entries from color0 to color255, or "default". Themes can
+// also be marshalled to and from JSON, as an object mapping names to styles
+// written the same way.
+type Theme map[string]tcell.Style
+
+// Returns the theme used when nothing else is set. The names it has are the
+// ones termutil uses.
+func DefaultTheme() Theme {
+	return Theme{
+		"selection":        tcell.StyleDefault.Reverse(true),
+		"hint":             tcell.StyleDefault.Dim(true),
+		"help":             tcell.StyleDefault.Reverse(true),
+		"border":           tcell.StyleDefault,
+		"border.title":     tcell.StyleDefault.Bold(true),
+		"menu.header":      tcell.StyleDefault.Bold(true),
+		"menu.disabled":    tcell.StyleDefault.Dim(true),
+		"menu.description": tcell.StyleDefault.Dim(true),
+		"menu.separator":   tcell.StyleDefault.Dim(true),
+		"tree.guide":       tcell.StyleDefault.Dim(true),
+		"table.header":     tcell.StyleDefault.Bold(true).Underline(true),
+		"preview.divider":  tcell.StyleDefault.Dim(true),
+		"pager.status":     tcell.StyleDefault.Reverse(true),
+		"pager.match":      tcell.StyleDefault.Reverse(true),
+		"pager.wrap":       tcell.StyleDefault.Dim(true),
+		"pager.gutter":     tcell.StyleDefault.Dim(true),
+		"message.info":     tcell.StyleDefault,
+		"message.warning":  tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true),
+		"message.error":    tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true),
+		"message.corner":   tcell.StyleDefault.Reverse(true),
+		"accelerator":      tcell.StyleDefault.Underline(true),
+		"button":           tcell.StyleDefault,
+		"button.focus":     tcell.StyleDefault.Reverse(true),
+	}
+}
+
+// CurrentTheme is the theme termutil's widgets are drawn with.
+var CurrentTheme = DefaultTheme()
+
+var defaultTheme = DefaultTheme()
+
+// Returns the style for name, from the theme if it's there, or else from
+// DefaultTheme, or else tcell.StyleDefault.
+func (t Theme) Style(name string) tcell.Style {
+	if st, ok := t[name]; ok {
+		return st
+	}
+	return defaultTheme[name]
+}
+
+// Returns the style for name from CurrentTheme.
+func themeStyle(name string) tcell.Style {
+	return CurrentTheme.Style(name)
+}
+
+// Returns style with the named theme style laid over it: the theme's
+// attributes are added, and its colours replace any that style leaves as the
+// default. This lets an item's own colours show through, say, a bold header.
+func themeOver(style tcell.Style, name string) tcell.Style {
+	fg, bg, attrs := style.Decompose()
+	tfg, tbg, tattrs := themeStyle(name).Decompose()
+	if fg == tcell.ColorDefault {
+		fg = tfg
+	}
+	if bg == tcell.ColorDefault {
+		bg = tbg
+	}
+	return tcell.StyleDefault.Foreground(fg).Background(bg).Attributes(attrs | tattrs)
+}
+
+var themeAttrs = []struct {
+	name string
+	attr tcell.AttrMask
+}{
+	{"bold", tcell.AttrBold},
+	{"italic", tcell.AttrItalic},
+	{"underline", tcell.AttrUnderline},
+	{"reverse", tcell.AttrReverse},
+	{"dim", tcell.AttrDim},
+	{"blink", tcell.AttrBlink},
+	{"strikethrough", tcell.AttrStrikeThrough},
+}
+
+// Parses a style written as described for Theme, e.g. "bold white on #303030".
+func ParseStyle(s string) (tcell.Style, error) {
+	style := tcell.StyleDefault
+	var attrs tcell.AttrMask
+	words := strings.Fields(strings.ToLower(s))
+	bg, bgset := false, false
+	colorset := false
+Words:
+	for _, word := range words {
+		for _, a := range themeAttrs {
+			if word == a.name {
+				if colorset || bg {
+					return style, fmt.Errorf("attribute %q must come before colours", word)
+				}
+				attrs |= a.attr
+				continue Words
+			}
+		}
+		if word == "on" {
+			if bg {
+				return style, fmt.Errorf("\"on\" given twice")
+			}
+			bg = true
+			continue
+		}
+		c, ok := tcell.ColorDefault, word == "default"
+		if !ok {
+			c, ok = parseColor(word)
+		}
+		if !ok {
+			return style, fmt.Errorf("unknown attribute or colour %q", word)
+		}
+		if bg && bgset {
+			return style, fmt.Errorf("more than one background colour")
+		} else if bg {
+			style = style.Background(c)
+			bgset = true
+		} else if colorset {
+			return style, fmt.Errorf("more than one foreground colour")
+		} else {
+			style = style.Foreground(c)
+		}
+		colorset = true
+	}
+	return style.Attributes(attrs), nil
+}
+
+// Writes a style as described for Theme; it can be read back with ParseStyle.
+func FormatStyle(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	words := make([]string, 0)
+	for _, a := range themeAttrs {
+		if attrs&a.attr != 0 {
+			words = append(words, a.name)
+		}
+	}
+	if fg != tcell.ColorDefault {
+		words = append(words, colorName(fg))
+	}
+	if bg != tcell.ColorDefault {
+		words = append(words, "on", colorName(bg))
+	}
+	if len(words) == 0 {
+		return "default"
+	}
+	return strings.Join(words, " ")
+}
+
+// Returns the name of the colour if it has one, or else "color" and its number
+// for a palette entry, or else its hex code, so that ParseStyle reads it back
+// as the same colour. Where a colour has several names, the first
+// alphabetically is used, so that saved themes don't change from run to run.
+func colorName(c tcell.Color) string {
+	best := ""
+	for name, nc := range tcell.ColorNames {
+		if nc == c && (best == "" || name < best) {
+			best = name
+		}
+	}
+	switch {
+	case best != "":
+		return best
+	case !c.IsRGB():
+		return fmt.Sprintf("color%d", c&^tcell.ColorValid)
+	}
+	return hexColor(c)
+}
+
+// Reads a theme in the text format described for Theme.
+func LoadTheme(r io.Reader) (Theme, error) {
+	t := Theme{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected name = style", lineno)
+		}
+		name := strings.TrimSpace(line[:eq])
+		style, err := ParseStyle(line[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		t[name] = style
+	}
+	return t, scanner.Err()
+}
+
+// Writes the theme in the text format described for Theme, sorted by name.
+func (t Theme) Save(w io.Writer) error {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s = %s\n", name, FormatStyle(t[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Theme) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(t))
+	for name, style := range t {
+		m[name] = FormatStyle(style)
+	}
+	return json.Marshal(m)
+}
+
+func (t *Theme) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*t = make(Theme, len(m))
+	for name, s := range m {
+		style, err := ParseStyle(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		(*t)[name] = style
+	}
+	return nil
+}
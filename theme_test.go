@@ -0,0 +1,137 @@
+package termutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseStyle(t *testing.T) {
+	def := tcell.StyleDefault
+	tests := []struct {
+		in   string
+		want tcell.Style
+		ok   bool
+	}{
+		{"", def, true},
+		{"default", def, true},
+		{"bold", def.Bold(true), true},
+		{"Bold Underline", def.Bold(true).Underline(true), true},
+		{"red", def.Foreground(tcell.ColorRed), true},
+		{"on red", def.Background(tcell.ColorRed), true},
+		{"bold white on #303030", def.Bold(true).Foreground(tcell.ColorWhite).Background(tcell.NewHexColor(0x303030)), true},
+		{"default on blue", def.Background(tcell.ColorBlue), true},
+		{"color196", def.Foreground(tcell.PaletteColor(196)), true},
+		{"on color0", def.Background(tcell.PaletteColor(0)), true},
+		{"color256", def, false},
+		{"red bold", def, false},
+		{"red blue", def, false},
+		{"bold on red blue", def, false},
+		{"on default red", def, false},
+		{"on on red", def, false},
+		{"sparkly", def, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseStyle(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseStyle(%q): error %v, want ok=%v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && got != tt.want {
+			t.Errorf("ParseStyle(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatStyleRoundTrip(t *testing.T) {
+	def := tcell.StyleDefault
+	styles := []tcell.Style{
+		def,
+		def.Reverse(true),
+		def.Bold(true).Italic(true).Underline(true).Reverse(true).Dim(true).Blink(true).StrikeThrough(true),
+		def.Foreground(tcell.ColorRed),
+		def.Foreground(tcell.PaletteColor(196)),
+		def.Background(tcell.PaletteColor(17)),
+		def.Foreground(tcell.NewRGBColor(255, 0, 0)),
+		def.Foreground(tcell.NewRGBColor(1, 2, 3)).Background(tcell.ColorAqua),
+		def.Foreground(tcell.ColorRebeccaPurple),
+	}
+	for _, st := range styles {
+		s := FormatStyle(st)
+		got, err := ParseStyle(s)
+		if err != nil {
+			t.Errorf("ParseStyle(FormatStyle(%v) = %q): %v", st, s, err)
+		} else if got != st {
+			t.Errorf("%v was written as %q and read back as %v", st, s, got)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	in := `# A comment
+; another
+
+selection = bold white on #303030
+ pager.status=reverse
+message.error = color160
+`
+	th, err := LoadTheme(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Theme{
+		"selection":     tcell.StyleDefault.Bold(true).Foreground(tcell.ColorWhite).Background(tcell.NewHexColor(0x303030)),
+		"pager.status":  tcell.StyleDefault.Reverse(true),
+		"message.error": tcell.StyleDefault.Foreground(tcell.PaletteColor(160)),
+	}
+	if len(th) != len(want) {
+		t.Errorf("got %d styles, want %d", len(th), len(want))
+	}
+	for name, st := range want {
+		if th[name] != st {
+			t.Errorf("%s = %v, want %v", name, th[name], st)
+		}
+	}
+	if th.Style("hint") != DefaultTheme()["hint"] {
+		t.Errorf("missing names don't fall back on DefaultTheme")
+	}
+
+	for _, bad := range []string{"selection bold", "selection = sparkly"} {
+		if _, err := LoadTheme(strings.NewReader("# ok\n" + bad)); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("LoadTheme(%q): error %v, want one for line 2", bad, err)
+		}
+	}
+}
+
+func TestThemeSaveRoundTrip(t *testing.T) {
+	th := DefaultTheme()
+	th["custom"] = tcell.StyleDefault.Foreground(tcell.PaletteColor(100)).Background(tcell.NewRGBColor(9, 8, 7))
+
+	var buf bytes.Buffer
+	if err := th.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTheme(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(th)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unmarshalled Theme
+	if err := json.Unmarshal(data, &unmarshalled); err != nil {
+		t.Fatal(err)
+	}
+	for name, st := range th {
+		if loaded[name] != st {
+			t.Errorf("text: %s = %v, want %v", name, loaded[name], st)
+		}
+		if unmarshalled[name] != st {
+			t.Errorf("JSON: %s = %v, want %v", name, unmarshalled[name], st)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package termutil
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Severity says how important a message is, which decides how it's drawn.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// Returns the style that messages of the given severity are drawn in.
+func (sev Severity) Style() tcell.Style {
+	switch sev {
+	case SeverityWarning:
+		return themeStyle("message.warning")
+	case SeverityError:
+		return themeStyle("message.error")
+	}
+	return themeStyle("message.info")
+}
+
+type message struct {
+	text    string
+	sev     Severity
+	expires time.Time
+}
+
+// MessageArea shows short messages, like "Saved 3 files", which go away by
+// themselves after a while - rather like the Emacs echo area. Messages are
+// drawn by calling Draw, typically from the refresh function passed to Prompt
+// and friends, or the callback passed to ChoiceIndexCallback. Prompt and
+// PressKey clear the bottom line after calling refresh, so set Corner, or
+// FromBottom to 1, for messages to show up alongside them. When a message is
+// added or expires, a *tcell.EventInterrupt whose Data is the MessageArea is
+// posted to the screen, so that whatever event loop is running redraws.
+type MessageArea struct {
+	// Duration is how long each message is shown for.
+	Duration time.Duration
+	// Corner shows messages stacked in the top-right corner, instead of on
+	// the bottom line of the screen.
+	Corner bool
+	// FromBottom is how many lines above the bottom of the screen messages
+	// are drawn when Corner isn't set.
+	FromBottom int
+
+	screen   tcell.Screen
+	mu       sync.Mutex
+	messages []message
+}
+
+// Creates a new MessageArea for the screen, with messages lasting 3 seconds.
+func NewMessageArea(screen tcell.Screen) *MessageArea {
+	return &MessageArea{Duration: 3 * time.Second, screen: screen}
+}
+
+// Adds a message of the given severity.
+func (m *MessageArea) Post(sev Severity, text string) {
+	m.mu.Lock()
+	expires := time.Now().Add(m.Duration)
+	m.messages = append(m.messages, message{text, sev, expires})
+	m.mu.Unlock()
+	time.AfterFunc(m.Duration, m.expire)
+	m.screen.PostEvent(tcell.NewEventInterrupt(m))
+}
+
+// Adds an informational message, formatted as for fmt.Sprintf.
+func (m *MessageArea) Info(format string, a ...interface{}) {
+	m.Post(SeverityInfo, fmt.Sprintf(format, a...))
+}
+
+// Adds a warning, formatted as for fmt.Sprintf.
+func (m *MessageArea) Warn(format string, a ...interface{}) {
+	m.Post(SeverityWarning, fmt.Sprintf(format, a...))
+}
+
+// Adds an error message, formatted as for fmt.Sprintf.
+func (m *MessageArea) Error(format string, a ...interface{}) {
+	m.Post(SeverityError, fmt.Sprintf(format, a...))
+}
+
+// Removes all messages.
+func (m *MessageArea) Clear() {
+	m.mu.Lock()
+	m.messages = nil
+	m.mu.Unlock()
+	m.screen.PostEvent(tcell.NewEventInterrupt(m))
+}
+
+// Returns the number of messages that haven't yet expired.
+func (m *MessageArea) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	return len(m.messages)
+}
+
+// Drops expired messages. Must be called with m.mu held.
+func (m *MessageArea) prune() {
+	now := time.Now()
+	live := m.messages[:0]
+	for _, msg := range m.messages {
+		if msg.expires.After(now) {
+			live = append(live, msg)
+		}
+	}
+	m.messages = live
+}
+
+func (m *MessageArea) expire() {
+	m.mu.Lock()
+	m.prune()
+	m.mu.Unlock()
+	m.screen.PostEvent(tcell.NewEventInterrupt(m))
+}
+
+// Draws the messages which haven't expired. On the bottom line (or the one
+// FromBottom lines up), only the newest is shown; in the corner, as many as will fit are shown, newest first,
+// with the theme's "message.corner" style laid over them.
+func (m *MessageArea) Draw() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	if len(m.messages) == 0 {
+		return
+	}
+	sx, sy := m.screen.Size()
+	if !m.Corner {
+		msg := m.messages[len(m.messages)-1]
+		y := sy - 1 - m.FromBottom
+		ClearLine(m.screen, sx, y)
+		printStringClip(m.screen, 0, y, 0, sx, msg.text, msg.sev.Style())
+		return
+	}
+	y := 0
+	for i := len(m.messages) - 1; i >= 0 && y < sy/2; i-- {
+		msg := m.messages[i]
+		text := ellipsize(" "+msg.text+" ", sx)
+		style := themeOver(msg.sev.Style(), "message.corner")
+		printStringClip(m.screen, sx-RunewidthStr(text), y, 0, sx, text, style)
+		y++
+	}
+}
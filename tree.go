@@ -0,0 +1,215 @@
+package termutil
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// TreeNode is a node in the tree shown by ChoiceTree.
+type TreeNode struct {
+	// Label is the text shown for the node.
+	Label string
+	// Data isn't used by termutil; it's there for the caller to find their
+	// way back to whatever the node represents.
+	Data interface{}
+	// Children are the node's children, if they are already known.
+	Children []*TreeNode
+	// Branch marks a node as having children even though Children is
+	// empty, so they can be loaded when the node is first expanded.
+	Branch bool
+	// Expanded is true if the node's children are shown.
+	Expanded bool
+
+	loaded bool
+}
+
+// IsBranch returns true if the node has, or may have, children.
+func (n *TreeNode) IsBranch() bool {
+	return n.Branch || len(n.Children) > 0
+}
+
+// treeRow is a visible row of the tree.
+type treeRow struct {
+	path []*TreeNode
+	// last records, for each level of the path, whether that node is the
+	// last of its siblings; it's used to draw the guide lines.
+	last []bool
+}
+
+func (r treeRow) node() *TreeNode {
+	return r.path[len(r.path)-1]
+}
+
+func flattenTree(rows []treeRow, nodes []*TreeNode, path []*TreeNode, last []bool) []treeRow {
+	for i, n := range nodes {
+		p := append(append([]*TreeNode{}, path...), n)
+		l := append(append([]bool{}, last...), i == len(nodes)-1)
+		rows = append(rows, treeRow{p, l})
+		if n.Expanded {
+			rows = flattenTree(rows, n.Children, p, l)
+		}
+	}
+	return rows
+}
+
+func treeGuides(row treeRow) string {
+	ret := ""
+	for i := 1; i < len(row.last); i++ {
+		if i == len(row.last)-1 {
+			if row.last[i] {
+				ret += "└─"
+			} else {
+				ret += "├─"
+			}
+		} else if row.last[i] {
+			ret += "  "
+		} else {
+			ret += "│ "
+		}
+	}
+	return ret
+}
+
+// Allows the user to select a node from a tree. RIGHT or C-f expands the
+// selected node, LEFT or C-b collapses it (or goes to its parent), and TAB
+// toggles it. When a node with Branch set is first expanded, load is called
+// with the path to it, and its result becomes the node's children; load may be
+// nil if the tree is already complete. Returns the path from the root to the
+// selected node, or nil if the user cancelled.
+func ChoiceTree(screen tcell.Screen, title string, roots []*TreeNode, load func([]*TreeNode) []*TreeNode) []*TreeNode {
+	return ChoiceTreeCallback(screen, title, roots, load, nil)
+}
+
+// As ChoiceTree, but calls a function after drawing the interface, passing it
+// the path to the selected node, screen width, and screen height.
+func ChoiceTreeCallback(screen tcell.Screen, title string, roots []*TreeNode, load func([]*TreeNode) []*TreeNode, f func(tcell.Screen, []*TreeNode, int, int)) []*TreeNode {
+	var selected *TreeNode
+	selection := 0
+	offset := 0
+	expand := func(row treeRow) {
+		n := row.node()
+		if !n.loaded && len(n.Children) == 0 && load != nil {
+			n.Children = load(row.path)
+		}
+		n.loaded = true
+		n.Expanded = len(n.Children) > 0
+		n.Branch = n.Expanded
+	}
+	for {
+		rows := flattenTree(nil, roots, nil, nil)
+		// Nodes the caller marked as expanded may not have been loaded yet.
+		for i := 0; i < len(rows); i++ {
+			if n := rows[i].node(); n.Expanded && n.Branch && !n.loaded && len(n.Children) == 0 {
+				expand(rows[i])
+				rows = flattenTree(nil, roots, nil, nil)
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		// Keep the same node selected as rows appear and disappear.
+		for i, row := range rows {
+			if row.node() == selected {
+				selection = i
+				break
+			}
+		}
+		if selection >= len(rows) {
+			selection = len(rows) - 1
+		}
+		selected = rows[selection].node()
+		nr := len(rows) - 1
+
+		sx, sy := screen.Size()
+		screen.HideCursor()
+		screen.Clear()
+		PrintString(screen, 0, 0, title)
+		for selection < offset {
+			offset -= 5
+			if offset < 0 {
+				offset = 0
+			}
+		}
+		for selection-offset >= sy-1 {
+			offset += 5
+			if offset >= nr {
+				offset = nr
+			}
+		}
+		for i := offset; i <= nr && i-offset < sy-1; i++ {
+			row := rows[i]
+			n := row.node()
+			x := printStringClip(screen, 2, i+1-offset, 2, sx, treeGuides(row), themeStyle("tree.guide"))
+			if n.Expanded {
+				x = printStringClip(screen, x, i+1-offset, 2, sx, "▾ ", tcell.StyleDefault)
+			} else if n.IsBranch() {
+				x = printStringClip(screen, x, i+1-offset, 2, sx, "▸ ", tcell.StyleDefault)
+			} else {
+				x += 2
+			}
+			printStringClip(screen, x, i+1-offset, 2, sx, n.Label, tcell.StyleDefault)
+		}
+		PrintString(screen, 0, (selection+1)-offset, ">")
+		if f != nil {
+			f(screen, rows[selection].path, sx, sy)
+		}
+		screen.Show()
+		ev := screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			row := rows[selection]
+			key := ParseTcellEvent(ev)
+			switch key {
+			case "C-v", "next":
+				selection += sy - 5
+				if selection > nr {
+					selection = nr
+				}
+			case "M-v", "prior":
+				selection -= sy - 5
+				if selection < 0 {
+					selection = 0
+				}
+			case "C-c", "C-g":
+				return nil
+			case "UP", "C-p":
+				if selection > 0 {
+					selection--
+				}
+			case "DOWN", "C-n":
+				if selection < nr {
+					selection++
+				}
+			case "RIGHT", "C-f":
+				if selected.Expanded {
+					// Move to the first child, if it has any.
+					if selection < nr && len(rows[selection+1].path) > len(row.path) {
+						selection++
+					}
+				} else if selected.IsBranch() {
+					expand(row)
+				}
+			case "LEFT", "C-b":
+				if selected.Expanded {
+					selected.Expanded = false
+				} else if len(row.path) > 1 {
+					selection = -1
+					selected = row.path[len(row.path)-2]
+					continue
+				}
+			case "TAB":
+				if selected.Expanded {
+					selected.Expanded = false
+				} else if selected.IsBranch() {
+					expand(row)
+				}
+			case "M-<":
+				selection = 0
+			case "M->":
+				selection = nr
+			case "RET":
+				return row.path
+			}
+			selected = rows[selection].node()
+		}
+	}
+}